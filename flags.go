@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// options holds the parsed command line.
+type options struct {
+	file        string         // Input file; empty means stdin
+	inputFormat jq.InputFormat // Empty means guess from the file extension
+	stream      bool           // Treat input as JSON Lines
+	slurp       bool           // Read all inputs into one array
+	nullInput   bool           // Run the filter against null
+	raw         bool           // Write strings without quotes
+	compact     bool           // One line per result
+	tab         bool           // Indent with tabs
+	yaml        bool           // Emit results as YAML
+	gron        bool           // Emit results as gron assignments
+	inferSchema bool           // Emit a JSON Schema inferred from the results
+	table       jq.TableFormat // Emit results as a CSV, TSV or Markdown table
+	vars        []namedArg
+	libPaths    []string // Module search paths from -L
+	filter      string   // Initial filter; empty means "."
+	batch       bool     // Print the result without starting the TUI
+	fromFile    string   // .jq file holding the initial filter
+	schema      string   // JSON Schema file the input is validated against
+	validateOut bool     // Also validate each result against the schema
+	maxResults  *int     // Display limits; nil keeps jq.DefaultLimits
+	maxBytes    *int
+	timeout     *time.Duration // Query timeout; nil uses $GIJQ_TIMEOUT or the default
+}
+
+// defaultTimeout stops runaway filters such as repeat(.) in the TUI.
+const defaultTimeout = 30 * time.Second
+
+// namedArg is a variable from --arg, --argjson, --slurpfile or --rawfile.
+type namedArg struct {
+	flag  string
+	name  string
+	value string // Literal text, JSON text or file path depending on flag
+}
+
+func parseArgs(args []string) (options, error) {
+	var opts options
+	args = expandShortFlags(args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var params []string
+		if p, ok := valueFlags[arg]; ok {
+			if i+p.count >= len(args) {
+				return opts, fmt.Errorf("%s takes %s", arg, p.what)
+			}
+			params = args[i+1 : i+1+p.count]
+			i += p.count
+		}
+		switch arg {
+		case "--ndjson", "--jsonl":
+			opts.stream = true
+		case "-s", "--slurp":
+			opts.slurp = true
+		case "-n", "--null-input":
+			opts.nullInput = true
+		case "-r", "--raw-output":
+			opts.raw = true
+		case "-c", "--compact-output":
+			opts.compact = true
+		case "--tab":
+			opts.tab = true
+		case "-y", "--yaml-output":
+			opts.yaml = true
+		case "--gron":
+			opts.gron = true
+		case "--ungron":
+			opts.inputFormat = jq.FormatGron
+		case "--infer-schema":
+			opts.inferSchema = true
+		case "--csv":
+			opts.table = jq.TableCSV
+		case "--tsv":
+			opts.table = jq.TableTSV
+		case "--markdown", "--md":
+			opts.table = jq.TableMarkdown
+		case "--input-format":
+			format, err := jq.ParseInputFormat(params[0])
+			if err != nil {
+				return opts, err
+			}
+			opts.inputFormat = format
+		case "-S", "--sort-keys":
+			// Object keys are always emitted sorted; accepted for jq compatibility.
+		case "--arg", "--argjson", "--slurpfile", "--rawfile":
+			opts.vars = append(opts.vars, namedArg{flag: arg, name: params[0], value: params[1]})
+		case "-f", "--filter":
+			opts.filter = params[0]
+		case "--from-file":
+			opts.fromFile = params[0]
+		case "--schema":
+			opts.schema = params[0]
+		case "--validate-results":
+			opts.validateOut = true
+		case "--max-results", "--max-output-bytes":
+			n, err := parseSize(params[0], arg == "--max-output-bytes")
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", arg, err)
+			}
+			if arg == "--max-results" {
+				opts.maxResults = &n
+			} else {
+				opts.maxBytes = &n
+			}
+		case "--timeout":
+			d, err := parseTimeout(params[0])
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", arg, err)
+			}
+			opts.timeout = &d
+		case "--batch", "--print":
+			opts.batch = true
+		case "-L", "--library-path":
+			opts.libPaths = append(opts.libPaths, params[0])
+		case "--":
+			if i+1 < len(args) {
+				if err := opts.setFile(args[i+1]); err != nil {
+					return opts, err
+				}
+			}
+			i = len(args)
+		default:
+			if strings.HasPrefix(arg, "-L") && len(arg) > 2 {
+				opts.libPaths = append(opts.libPaths, arg[2:])
+				continue
+			}
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				return opts, fmt.Errorf("unknown option %q", arg)
+			}
+			if err := opts.setFile(arg); err != nil {
+				return opts, err
+			}
+		}
+	}
+	if opts.yaml && opts.table != "" {
+		return opts, fmt.Errorf("--yaml-output cannot be combined with --%s", opts.table)
+	}
+	if opts.gron && (opts.yaml || opts.table != "") {
+		other := "--yaml-output"
+		if opts.table != "" {
+			other = "--" + string(opts.table)
+		}
+		return opts, fmt.Errorf("--gron cannot be combined with %s", other)
+	}
+	if opts.inferSchema && (opts.gron || opts.table != "") {
+		return opts, fmt.Errorf("--infer-schema cannot be combined with --gron or table output")
+	}
+	if opts.validateOut && opts.schema == "" {
+		return opts, fmt.Errorf("--validate-results requires --schema")
+	}
+	if opts.filter != "" && opts.fromFile != "" {
+		return opts, fmt.Errorf("-f and --from-file cannot be used together")
+	}
+	return opts, nil
+}
+
+// limits returns the display limits, defaults overridden by any flags.
+func (o options) limits() jq.Limits {
+	limits := jq.DefaultLimits
+	if o.maxResults != nil {
+		limits.Results = *o.maxResults
+	}
+	if o.maxBytes != nil {
+		limits.Bytes = *o.maxBytes
+	}
+	return limits
+}
+
+// queryTimeout returns the TUI query timeout: the flag, else the value of
+// env, else defaultTimeout.
+func (o options) queryTimeout(env string) (time.Duration, error) {
+	if o.timeout != nil {
+		return *o.timeout, nil
+	}
+	if env == "" {
+		return defaultTimeout, nil
+	}
+	d, err := parseTimeout(env)
+	if err != nil {
+		return 0, fmt.Errorf("GIJQ_TIMEOUT: %w", err)
+	}
+	return d, nil
+}
+
+// parseTimeout parses a Go duration such as 500ms or 1m. A bare number is
+// seconds; 0 disables the timeout.
+func parseTimeout(text string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(text, 64); err == nil && secs >= 0 {
+		// inf and huge values would overflow, silently disabling the timeout
+		ns := secs * float64(time.Second)
+		if ns >= math.MaxInt64 {
+			return 0, fmt.Errorf("duration %q is out of range", text)
+		}
+		return time.Duration(ns), nil
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+	return d, nil
+}
+
+// parseSize parses a non-negative count. Sizes in bytes may end in K, M or
+// G (powers of 1024), optionally followed by B.
+func parseSize(text string, bytes bool) (int, error) {
+	digits, scale := text, 1
+	if bytes {
+		upper := strings.TrimSuffix(strings.ToUpper(text), "B")
+		for i, unit := range []string{"K", "M", "G"} {
+			if strings.HasSuffix(upper, unit) {
+				digits, scale = strings.TrimSuffix(upper, unit), 1<<(10*(i+1))
+				break
+			}
+		}
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	if n > math.MaxInt/scale {
+		return 0, fmt.Errorf("size %q is out of range", text)
+	}
+	return n * scale, nil
+}
+
+func (o *options) setFile(path string) error {
+	if o.file != "" {
+		return fmt.Errorf("unexpected argument %q: only one input file is supported", path)
+	}
+	o.file = path
+	return nil
+}
+
+// resolveVariables reads and decodes the values of named arguments.
+func resolveVariables(args []namedArg) (map[string]any, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]any, len(args))
+	for _, arg := range args {
+		var value any
+		switch arg.flag {
+		case "--arg":
+			value = arg.value
+		case "--argjson":
+			values, err := jq.DecodeValues([]byte(arg.value))
+			if err != nil || len(values) != 1 {
+				return nil, fmt.Errorf("--argjson %s: invalid JSON text %q", arg.name, arg.value)
+			}
+			value = values[0]
+		case "--slurpfile":
+			data, err := os.ReadFile(arg.value)
+			if err != nil {
+				return nil, fmt.Errorf("--slurpfile %s: %w", arg.name, err)
+			}
+			values, err := jq.DecodeValues(data)
+			if err != nil {
+				return nil, fmt.Errorf("--slurpfile %s: %w", arg.name, err)
+			}
+			if values == nil {
+				values = []any{}
+			}
+			value = values
+		case "--rawfile":
+			data, err := os.ReadFile(arg.value)
+			if err != nil {
+				return nil, fmt.Errorf("--rawfile %s: %w", arg.name, err)
+			}
+			value = string(data)
+		}
+		vars[arg.name] = value
+	}
+	return vars, nil
+}
+
+// shortBoolFlags lists single-letter switches that may be grouped, as in -nr.
+const shortBoolFlags = "snrcyS"
+
+// flagParam describes the values an option consumes from the arguments
+// after it. Those are values, never grouped switches.
+type flagParam struct {
+	count int
+	what  string // Named in the error when values are missing
+}
+
+var valueFlags = map[string]flagParam{
+	"--arg":              {2, "two parameters (e.g. --arg name value)"},
+	"--argjson":          {2, "two parameters (e.g. --argjson name value)"},
+	"--slurpfile":        {2, "two parameters (e.g. --slurpfile name value)"},
+	"--rawfile":          {2, "two parameters (e.g. --rawfile name value)"},
+	"-f":                 {1, "a filter"},
+	"--filter":           {1, "a filter"},
+	"--from-file":        {1, "a file"},
+	"--schema":           {1, "a file"},
+	"--input-format":     {1, "a format"},
+	"-L":                 {1, "a directory"},
+	"--library-path":     {1, "a directory"},
+	"--max-results":      {1, "a number"},
+	"--max-output-bytes": {1, "a number"},
+	"--timeout":          {1, "a duration"},
+}
+
+// expandShortFlags splits grouped short switches such as -sn into -s -n.
+// Only arguments in option position are split; option values and everything
+// after -- are passed through.
+func expandShortFlags(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if p, ok := valueFlags[arg]; ok {
+			end := min(i+1+p.count, len(args))
+			out = append(out, args[i:end]...)
+			i = end - 1
+			continue
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && isShortBoolGroup(arg[1:]) {
+			for _, ch := range arg[1:] {
+				out = append(out, "-"+string(ch))
+			}
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+func isShortBoolGroup(s string) bool {
+	for _, ch := range s {
+		if !strings.ContainsRune(shortBoolFlags, ch) {
+			return false
+		}
+	}
+	return true
+}
@@ -4,8 +4,18 @@ import (
 	"strings"
 )
 
+// Kind identifies what is being completed
+type Kind int
+
+const (
+	KindKey      Kind = iota // Object key or array index after a path
+	KindVariable             // $variable reference
+	KindFunction             // Function call, including module::name
+)
+
 // Context represents the parsed autocomplete context
 type Context struct {
+	Kind       Kind
 	Path       string // Valid jq path prefix
 	Incomplete string // Partial key being typed
 	StartPos   int    // Where incomplete begins in filter
@@ -17,6 +27,13 @@ func Parse(filter string) Context {
 		return Context{Path: ".", Incomplete: "", StartPos: 0}
 	}
 
+	if start := trailingVariableStart(filter); start >= 0 {
+		return Context{Kind: KindVariable, Path: ".", Incomplete: filter[start:], StartPos: start}
+	}
+	if start := trailingFunctionStart(filter); start >= 0 {
+		return Context{Kind: KindFunction, Path: ".", Incomplete: filter[start:], StartPos: start}
+	}
+
 	// Find the last segment to autocomplete
 	// Look for last pipe first (indicates new expression)
 	pipeIdx := strings.LastIndex(filter, "|")
@@ -55,6 +72,47 @@ func Parse(filter string) Context {
 	}
 }
 
+// trailingVariableStart returns the index of the '$' starting a variable
+// name at the end of filter, or -1 when the filter does not end in one.
+func trailingVariableStart(s string) int {
+	i := len(s)
+	for i > 0 && isIdentChar(s[i-1]) {
+		i--
+	}
+	if i > 0 && s[i-1] == '$' {
+		return i - 1
+	}
+	return -1
+}
+
+// trailingFunctionStart returns the start of a bare identifier (optionally
+// module-qualified with ::) at the end of filter, or -1. Identifiers after
+// '.' are keys and are not treated as function names.
+func trailingFunctionStart(s string) int {
+	i := len(s)
+	for i > 0 && (isIdentChar(s[i-1]) || s[i-1] == ':') {
+		i--
+	}
+	if i == len(s) {
+		return -1
+	}
+	first := s[i]
+	if first == ':' || (first >= '0' && first <= '9') {
+		return -1
+	}
+	if i > 0 && (s[i-1] == '.' || s[i-1] == '$') {
+		return -1
+	}
+	return i
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' ||
+		(ch >= 'a' && ch <= 'z') ||
+		(ch >= 'A' && ch <= 'Z') ||
+		(ch >= '0' && ch <= '9')
+}
+
 // findLastKeyDot finds the last '.' that starts a key access
 // (not inside brackets)
 func findLastKeyDot(s string) int {
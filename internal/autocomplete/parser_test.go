@@ -31,6 +31,16 @@ func TestParse(t *testing.T) {
 		{"pipe then dot", ".foo | .", ".", "", 8},
 		{"pipe then key", ".foo | .bar", ".", "bar", 8},
 		{"pipe then nested", ".foo | .bar.", ".bar", "", 12},
+
+		// Variables
+		{"bare dollar", "$", ".", "$", 0},
+		{"partial variable", ".a == $na", ".", "$na", 6},
+		{"variable in call", "select(.id == $u", ".", "$u", 14},
+
+		// Functions
+		{"bare function", "sel", ".", "sel", 0},
+		{"function after pipe", ".users | ma", ".", "ma", 9},
+		{"module function", "import \"u\" as u; u::do", ".", "u::do", 17},
 	}
 
 	for _, tt := range tests {
@@ -48,3 +58,25 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   Kind
+	}{
+		{".foo", KindKey},
+		{".foo | .ba", KindKey},
+		{"$us", KindVariable},
+		{"map(sel", KindFunction},
+		{".items | length", KindFunction},
+		{".a + 12", KindKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			if got := Parse(tt.filter).Kind; got != tt.want {
+				t.Errorf("Parse(%q).Kind = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
@@ -22,9 +22,18 @@ func (s *Service) ParseContext(filter string) Context {
 	return Parse(filter)
 }
 
+// builtinVariables are always defined by jq.
+var builtinVariables = []string{"$ENV", "$__loc__"}
+
 // Suggest returns matching keys for the current filter
 func (s *Service) Suggest(filter string) ([]string, Context) {
 	ctx := s.ParseContext(filter)
+	switch ctx.Kind {
+	case KindVariable:
+		return filterByPrefix(s.VariableNames(), ctx.Incomplete), ctx
+	case KindFunction:
+		return filterByPrefix(s.jq.Functions(filter), ctx.Incomplete), ctx
+	}
 
 	// If filter contains a pipe, resolve context from the left side's output
 	var keys []string
@@ -43,17 +52,31 @@ func (s *Service) Suggest(filter string) ([]string, Context) {
 		return []string{}, ctx
 	}
 
-	// Filter by prefix (case-insensitive)
+	return filterByPrefix(keys, ctx.Incomplete), ctx
+}
+
+// VariableNames lists every $variable a filter can reference.
+func (s *Service) VariableNames() []string {
+	vars := s.jq.Variables()
+	names := make([]string, 0, len(vars)+len(builtinVariables))
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	return append(names, builtinVariables...)
+}
+
+// filterByPrefix returns the sorted candidates matching prefix, ignoring case.
+func filterByPrefix(candidates []string, prefix string) []string {
 	var matches []string
-	incLower := strings.ToLower(ctx.Incomplete)
-	for _, k := range keys {
+	incLower := strings.ToLower(prefix)
+	for _, k := range candidates {
 		if strings.HasPrefix(strings.ToLower(k), incLower) {
 			matches = append(matches, k)
 		}
 	}
 
 	sort.Strings(matches)
-	return matches, ctx
+	return matches
 }
 
 // resolveKeysAfterPipe determines available keys from the output of the left side of a pipe
@@ -74,3 +74,40 @@ func equalSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestSuggestVariables(t *testing.T) {
+	jqSvc, err := jq.NewServiceWithConfig([]byte(`{"users":[]}`), jq.Config{
+		Variables: map[string]any{"user": "alice", "limit": 10},
+	})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	svc := NewService(jqSvc)
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"$", []string{"$ARGS", "$ENV", "$__loc__", "$limit", "$user"}},
+		{".users[] | select(.name == $u", []string{"$user"}},
+		{"$L", []string{"$limit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			suggestions, ctx := svc.Suggest(tt.filter)
+			if ctx.Kind != KindVariable {
+				t.Fatalf("Kind = %v, want KindVariable", ctx.Kind)
+			}
+			if !equalSlices(suggestions, tt.want) {
+				t.Errorf("Suggest(%q) = %v, want %v", tt.filter, suggestions, tt.want)
+			}
+		})
+	}
+
+	_, ctx := svc.Suggest(".users[] | select(.name == $u")
+	got := svc.Apply(".users[] | select(.name == $u", ctx, "$user")
+	if got != ".users[] | select(.name == $user" {
+		t.Errorf("Apply = %q", got)
+	}
+}
@@ -0,0 +1,567 @@
+package jq
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Formatted output is cached a page of lines at a time, keeping only the
+// most recently used pages.
+const (
+	bufferPageLines = 512
+	bufferMaxPages  = 64
+)
+
+// ResultBuffer collects a filter's outputs as evaluation produces them and
+// formats them into lines on demand. The start of a large result can be
+// shown before the rest exists, and its formatted text is never held in
+// full: each value's line count is measured without formatting it, and
+// only the pages being looked at are laid out.
+//
+// Tables, gron and inferred schemas are laid out from every result at once,
+// so for those the lines appear when evaluation finishes.
+//
+// Evaluation stops once the buffer's limits are reached, and the buffer
+// reports itself truncated.
+type ResultBuffer struct {
+	opts   OutputOptions
+	whole  bool // opts lay out all results together
+	limits Limits
+
+	mu        sync.Mutex
+	grown     *sync.Cond
+	values    []any
+	starts    []int // Line each value starts on
+	lines     int
+	size      int // Estimated formatted bytes
+	truncated bool
+	err       error
+	finished  bool
+	done      chan struct{}
+
+	block    []string // Lines of a whole-output layout, once finished
+	pages    map[int][]string
+	recent   []int // Cached pages, least recently used first
+	maxWidth int
+}
+
+func newResultBuffer(opts OutputOptions, limits Limits) *ResultBuffer {
+	b := &ResultBuffer{
+		opts:   opts,
+		whole:  wholeOutput(opts),
+		limits: limits,
+		done:   make(chan struct{}),
+		pages:  map[int][]string{},
+	}
+	b.grown = sync.NewCond(&b.mu)
+	return b
+}
+
+// NewResultBuffer returns a finished buffer holding values.
+func NewResultBuffer(values []any, opts OutputOptions) *ResultBuffer {
+	b := newResultBuffer(opts, Limits{})
+	for _, v := range values {
+		b.append(v)
+	}
+	b.finish(nil)
+	return b
+}
+
+// Stream starts evaluating filter in the background and returns the buffer
+// its outputs are collected in, up to the service's limits. Cancelling ctx
+// stops the evaluation.
+func (s *Service) Stream(ctx context.Context, filter string) *ResultBuffer {
+	return s.StreamWithTimeout(ctx, filter, 0)
+}
+
+// StreamWithTimeout is Stream with evaluation stopped once timeout has passed.
+// The timeout starts when the input has loaded, so a slow load does not count
+// against it. A timeout of zero means none.
+func (s *Service) StreamWithTimeout(ctx context.Context, filter string, timeout time.Duration) *ResultBuffer {
+	b := newResultBuffer(s.OutputOptions(), s.limits)
+	go func() {
+		if timeout > 0 {
+			if err := s.waitLoaded(ctx); err != nil {
+				b.finish(err)
+				return
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		b.finish(s.run(ctx, filter, b.append))
+	}()
+	return b
+}
+
+// append adds v to the buffer, reporting false once the limits are reached.
+// The first output is always kept, however large.
+func (b *ResultBuffer) append(v any) bool {
+	n, size := measureValue(v, b.opts)
+	if b.whole {
+		n = 0 // Counted once the layout is known
+	}
+
+	b.mu.Lock()
+	defer b.grown.Broadcast()
+	defer b.mu.Unlock()
+	if len(b.values) > 0 {
+		full := b.limits.Results > 0 && len(b.values) >= b.limits.Results
+		if b.limits.Bytes > 0 && b.size+size > b.limits.Bytes {
+			full = true
+		}
+		if full {
+			b.truncated = true
+			return false
+		}
+		if b.yamlDocuments() {
+			n++ // The --- separator before the document
+		}
+	}
+	b.values = append(b.values, v)
+	b.starts = append(b.starts, b.lines)
+	b.lines += n
+	b.size += size + 1
+	return true
+}
+
+func (b *ResultBuffer) finish(err error) {
+	b.mu.Lock()
+	if err == nil && b.whole {
+		result := formatOutput(b.values, b.opts)
+		err = result.Error
+		b.block = strings.Split(result.Raw, "\n")
+		b.lines = len(b.block)
+		for _, line := range b.block {
+			b.maxWidth = max(b.maxWidth, runewidth.StringWidth(line))
+		}
+	}
+	b.err = err
+	b.finished = true
+	b.mu.Unlock()
+	close(b.done)
+	b.grown.Broadcast()
+}
+
+// yamlDocuments reports whether results are separated by --- lines.
+func (b *ResultBuffer) yamlDocuments() bool {
+	return b.opts.YAML && !b.opts.Compact
+}
+
+// WaitLines blocks until the buffer holds at least n lines, evaluation has
+// finished or timeout has passed.
+func (b *ResultBuffer) WaitLines(n int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		b.mu.Lock()
+		b.grown.Broadcast()
+		b.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	b.mu.Lock()
+	for b.lines < n && !b.finished && time.Now().Before(deadline) {
+		b.grown.Wait()
+	}
+	b.mu.Unlock()
+}
+
+// Truncated reports whether evaluation was stopped by the limits, leaving
+// out later outputs.
+func (b *ResultBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
+
+// Done is closed once evaluation has finished.
+func (b *ResultBuffer) Done() <-chan struct{} {
+	return b.done
+}
+
+// Finished reports whether evaluation has finished.
+func (b *ResultBuffer) Finished() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.finished
+}
+
+// Err returns the error that stopped evaluation, if any.
+func (b *ResultBuffer) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Values returns the outputs collected so far.
+func (b *ResultBuffer) Values() []any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.values[:len(b.values):len(b.values)]
+}
+
+// Len returns the number of output lines so far.
+func (b *ResultBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lines
+}
+
+// ValueAt returns the index of the value output line n belongs to and the
+// line's offset within that value's layout. The --- separator before a YAML
+// document has offset -1. Whole-output layouts have no per-value lines.
+func (b *ResultBuffer) ValueAt(n int) (index, offset int, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.whole || n < 0 || n >= b.lines {
+		return 0, 0, false
+	}
+	index = sort.Search(len(b.starts), func(i int) bool { return b.starts[i] > n }) - 1
+	offset = n - b.starts[index]
+	if index > 0 && b.yamlDocuments() {
+		offset--
+	}
+	return index, offset, true
+}
+
+// MaxWidth returns the display width of the widest line formatted so far.
+func (b *ResultBuffer) MaxWidth() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.maxWidth
+}
+
+// Text returns the whole output as one string, as printed in batch mode.
+func (b *ResultBuffer) Text() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.block != nil {
+		return strings.Join(b.block, "\n")
+	}
+	return formatResults(b.values, b.opts)
+}
+
+// Print writes the whole output to w followed by a newline, as batch mode
+// does.
+func (b *ResultBuffer) Print(w io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.block != nil {
+		return writeWhole(w, Result{Raw: strings.Join(b.block, "\n")})
+	}
+	out := newResultWriter(w, b.opts)
+	for _, v := range b.values {
+		if err := out.write(v); err != nil {
+			return err
+		}
+	}
+	return out.close()
+}
+
+// Lines returns output lines start up to end, formatting any pages not in
+// the cache.
+func (b *ResultBuffer) Lines(start, end int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start, end = max(start, 0), min(end, b.lines)
+	if start >= end {
+		return nil
+	}
+	if b.block != nil {
+		return b.block[start:end]
+	}
+
+	out := make([]string, 0, end-start)
+	for p := start / bufferPageLines; p*bufferPageLines < end; p++ {
+		page := b.page(p)
+		first := p * bufferPageLines
+		lo := max(start-first, 0)
+		hi := min(end-first, len(page))
+		if lo < hi {
+			out = append(out, page[lo:hi]...)
+		}
+	}
+	return out
+}
+
+// page returns the lines of page p. Pages are cached once complete; the
+// last one may still grow while evaluation runs.
+func (b *ResultBuffer) page(p int) []string {
+	if lines, ok := b.pages[p]; ok {
+		b.touch(p)
+		return lines
+	}
+
+	lo := p * bufferPageLines
+	hi := min(lo+bufferPageLines, b.lines)
+	lines := make([]string, 0, hi-lo)
+	// The last value starting at or before lo
+	i := sort.Search(len(b.starts), func(i int) bool { return b.starts[i] > lo }) - 1
+	for ; i < len(b.values) && lo+len(lines) < hi; i++ {
+		skip := lo + len(lines) - b.starts[i]
+		lines = b.appendValueLines(lines, i, skip, hi-lo-len(lines))
+	}
+	for _, line := range lines {
+		b.maxWidth = max(b.maxWidth, runewidth.StringWidth(line))
+	}
+
+	if len(lines) == bufferPageLines || b.finished {
+		b.pages[p] = lines
+		b.touch(p)
+		if len(b.recent) > bufferMaxPages {
+			delete(b.pages, b.recent[0])
+			b.recent = b.recent[1:]
+		}
+	}
+	return lines
+}
+
+// touch marks page p as the most recently used.
+func (b *ResultBuffer) touch(p int) {
+	for i, q := range b.recent {
+		if q == p {
+			b.recent = append(b.recent[:i], b.recent[i+1:]...)
+			break
+		}
+	}
+	b.recent = append(b.recent, p)
+}
+
+// appendValueLines appends up to limit lines of value i, skipping the first
+// skip.
+func (b *ResultBuffer) appendValueLines(out []string, i, skip, limit int) []string {
+	if i > 0 && b.yamlDocuments() {
+		if skip == 0 {
+			out = append(out, "---")
+			limit--
+		} else {
+			skip--
+		}
+	}
+	if limit <= 0 {
+		return out
+	}
+
+	v := b.values[i]
+	if _, isString := v.(string); b.opts.YAML || b.opts.Compact || (b.opts.Raw && isString) {
+		lines := strings.Split(FormatValue(v, b.opts), "\n")
+		if skip >= len(lines) {
+			return out
+		}
+		return append(out, lines[skip:min(skip+limit, len(lines))]...)
+	}
+
+	w := jsonLines{indent: b.opts.Indent(), skip: skip, end: skip + limit, out: out}
+	w.value("", v, 0, false)
+	return w.out
+}
+
+// measureValue returns the number of lines v takes when formatted with
+// opts and an estimate of its size in bytes. Only YAML is formatted to find
+// out; JSON is measured by walking the value.
+func measureValue(v any, opts OutputOptions) (lines, size int) {
+	if s, ok := v.(string); ok && opts.Raw {
+		return strings.Count(s, "\n") + 1, len(s)
+	}
+	if opts.YAML {
+		text := FormatValue(v, opts)
+		return strings.Count(text, "\n") + 1, len(text)
+	}
+	lines, size = jsonMeasure(v, len(opts.Indent()), 0)
+	if opts.Compact {
+		lines = 1
+	}
+	return lines, size
+}
+
+// jsonMeasure returns the lines and approximate bytes v takes as JSON
+// indented by indent bytes per level at depth. String escapes are not
+// counted.
+func jsonMeasure(v any, indent, depth int) (lines, size int) {
+	// The newline and indentation before a line at depth d
+	lineStart := func(d int) int {
+		if indent == 0 {
+			return 0
+		}
+		return 1 + indent*d
+	}
+	member := func(child any) {
+		l, s := jsonMeasure(child, indent, depth+1)
+		lines += l
+		size += lineStart(depth+1) + s + 1 // And a comma
+	}
+
+	switch val := v.(type) {
+	case []any:
+		if len(val) == 0 {
+			return 1, 2
+		}
+		for _, item := range val {
+			member(item)
+		}
+		return lines + 2, size + 1 + lineStart(depth)
+	case map[string]any:
+		if len(val) == 0 {
+			return 1, 2
+		}
+		for k, item := range val {
+			member(item)
+			size += len(k) + 3 // Quotes and colon
+			if indent > 0 {
+				size++
+			}
+		}
+		return lines + 2, size + 1 + lineStart(depth)
+	case string:
+		return 1, len(val) + 2
+	case json.Number:
+		return 1, len(val)
+	case bool:
+		if val {
+			return 1, 4
+		}
+		return 1, 5
+	case nil:
+		return 1, 4
+	}
+	return 1, len(FormatValue(v, OutputOptions{Compact: true}))
+}
+
+// jsonLineCount returns the number of lines v takes as indented JSON.
+func jsonLineCount(v any) int {
+	switch val := v.(type) {
+	case []any:
+		if len(val) == 0 {
+			return 1
+		}
+		n := 2
+		for _, item := range val {
+			n += jsonLineCount(item)
+		}
+		return n
+	case map[string]any:
+		if len(val) == 0 {
+			return 1
+		}
+		n := 2
+		for _, item := range val {
+			n += jsonLineCount(item)
+		}
+		return n
+	}
+	return 1
+}
+
+// PathAtLine returns the path of the value shown on line n of v laid out as
+// indented JSON. The closing bracket of an array or object belongs to it.
+// Only the elements before the line are walked, and those are counted
+// rather than formatted.
+func PathAtLine(v any, n int) []any {
+	var path []any
+	for n > 0 {
+		n-- // The opening bracket
+		var key, child any
+		found := false
+		visit := func(k, item any) bool {
+			if c := jsonLineCount(item); n >= c {
+				n -= c
+				return true
+			}
+			key, child, found = k, item, true
+			return false
+		}
+		switch val := v.(type) {
+		case []any:
+			for i, item := range val {
+				if !visit(i, item) {
+					break
+				}
+			}
+		case map[string]any:
+			for _, k := range sortedKeys(val) {
+				if !visit(k, val[k]) {
+					break
+				}
+			}
+		}
+		if !found {
+			return path // A scalar, or the closing bracket
+		}
+		path, v = append(path, key), child
+	}
+	return path
+}
+
+// jsonLines lays out indented JSON a line at a time, keeping the lines from
+// skip up to end. Elements wholly before skip are counted, not formatted,
+// and nothing after end is visited.
+type jsonLines struct {
+	indent    string
+	skip, end int
+	line      int // Number of the next line
+	out       []string
+}
+
+func (w *jsonLines) value(prefix string, v any, depth int, comma bool) {
+	suffix := ""
+	if comma {
+		suffix = ","
+	}
+	switch val := v.(type) {
+	case []any:
+		if len(val) > 0 {
+			w.emit(depth, prefix+"[")
+			for i, item := range val {
+				if w.line >= w.end {
+					return
+				}
+				w.element(nil, item, depth+1, i < len(val)-1)
+			}
+			w.emit(depth, "]"+suffix)
+			return
+		}
+	case map[string]any:
+		if len(val) > 0 {
+			w.emit(depth, prefix+"{")
+			for i, k := range sortedKeys(val) {
+				if w.line >= w.end {
+					return
+				}
+				w.element(k, val[k], depth+1, i < len(val)-1)
+			}
+			w.emit(depth, "}"+suffix)
+			return
+		}
+	}
+	w.emit(depth, prefix+FormatValue(v, OutputOptions{Compact: true})+suffix)
+}
+
+// element lays out an array element, or an object member when key is a
+// string, unless it ends before skip.
+func (w *jsonLines) element(key any, v any, depth int, comma bool) {
+	if w.line < w.skip {
+		if n := jsonLineCount(v); w.line+n <= w.skip {
+			w.line += n
+			return
+		}
+	}
+	prefix := ""
+	if k, ok := key.(string); ok {
+		prefix = FormatValue(k, OutputOptions{}) + ": "
+	}
+	w.value(prefix, v, depth, comma)
+}
+
+func (w *jsonLines) emit(depth int, text string) {
+	if w.line >= w.skip && w.line < w.end {
+		w.out = append(w.out, strings.Repeat(w.indent, depth)+text)
+	}
+	w.line++
+}
@@ -0,0 +1,225 @@
+package jq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultBufferMatchesFormattedOutput(t *testing.T) {
+	// Enough values to span several pages, with containers crossing page
+	// boundaries
+	var values []any
+	for i := 0; i < 300; i++ {
+		values = append(values, map[string]any{
+			"id":    json.Number(fmt.Sprint(i)),
+			"tags":  []any{"a", "b\nc", []any{}},
+			"empty": map[string]any{},
+			"":      nil,
+		})
+	}
+	values = append(values, "multi\nline", []any{}, json.Number("1"))
+
+	for _, opts := range []OutputOptions{
+		{},
+		{Tab: true},
+		{Compact: true},
+		{Raw: true},
+		{YAML: true},
+		{YAML: true, Compact: true},
+		{Gron: true},
+	} {
+		t.Run(fmt.Sprintf("%+v", opts), func(t *testing.T) {
+			want := strings.Split(formatOutput(values, opts).Raw, "\n")
+			b := NewResultBuffer(values, opts)
+			if b.Len() != len(want) {
+				t.Fatalf("Len = %d, want %d", b.Len(), len(want))
+			}
+			if b.Text() != strings.Join(want, "\n") {
+				t.Fatal("Text differs from the formatted output")
+			}
+			// Read out of order so pages are formatted from a cold start
+			for _, start := range []int{len(want) - 700, 511, 0, 1000, 37} {
+				start = min(max(start, 0), len(want)-1)
+				end := min(start+700, len(want))
+				got := b.Lines(start, end)
+				for i, line := range got {
+					if line != want[start+i] {
+						t.Fatalf("line %d = %q, want %q", start+i, line, want[start+i])
+					}
+				}
+				if len(got) != end-start {
+					t.Fatalf("Lines(%d, %d) returned %d lines", start, end, len(got))
+				}
+			}
+		})
+	}
+}
+
+func TestPathAtLine(t *testing.T) {
+	values := []any{
+		map[string]any{"a": []any{json.Number("1"), map[string]any{"b": json.Number("2")}}, "c": json.Number("3")},
+		"x",
+	}
+	b := NewResultBuffer(values, OutputOptions{})
+	want := []string{".", ".a", ".a[0]", ".a[1]", ".a[1].b", ".a[1]", ".a", ".c", ".", "."}
+	if b.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", b.Len(), len(want))
+	}
+	for line, w := range want {
+		index, offset, ok := b.ValueAt(line)
+		if !ok {
+			t.Fatalf("ValueAt(%d) found no value", line)
+		}
+		if got := FormatPath(PathAtLine(values[index], offset)); got != w {
+			t.Errorf("line %d (%q) path = %s, want %s", line, b.Lines(line, line+1)[0], got, w)
+		}
+	}
+	if index, _, _ := b.ValueAt(9); index != 1 {
+		t.Errorf("last line belongs to value %d, want 1", index)
+	}
+	if _, _, ok := b.ValueAt(len(want)); ok {
+		t.Error("ValueAt past the end should fail")
+	}
+
+	// The --- line before a YAML document
+	b = NewResultBuffer([]any{"a", "b"}, OutputOptions{YAML: true})
+	if index, offset, _ := b.ValueAt(1); index != 1 || offset != -1 {
+		t.Errorf("separator = value %d offset %d", index, offset)
+	}
+	if _, _, ok := NewResultBuffer(values, OutputOptions{Gron: true}).ValueAt(0); ok {
+		t.Error("gron output has no per-value lines")
+	}
+}
+
+func TestResultBufferKeepsRecentPages(t *testing.T) {
+	values := make([]any, bufferPageLines*(bufferMaxPages+10))
+	for i := range values {
+		values[i] = json.Number(fmt.Sprint(i))
+	}
+	b := NewResultBuffer(values, OutputOptions{})
+	for start := 0; start < len(values); start += bufferPageLines {
+		b.Lines(start, start+1)
+	}
+	if len(b.pages) != bufferMaxPages {
+		t.Fatalf("cached %d pages, want %d", len(b.pages), bufferMaxPages)
+	}
+	if got := b.Lines(0, 1); got[0] != "0" {
+		t.Fatalf("evicted page reformatted as %q", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	svc, err := NewService([]byte(`{"items":[1,2,3]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := svc.Stream(context.Background(), ".items[]")
+	<-b.Done()
+	if got := b.Lines(0, b.Len()); strings.Join(got, ",") != "1,2,3" {
+		t.Fatalf("lines = %q", got)
+	}
+
+	b = svc.Stream(context.Background(), ".[")
+	b.WaitLines(1, time.Minute)
+	if b.Err() == nil || !b.Finished() {
+		t.Fatal("parse error should finish the stream with an error")
+	}
+
+	// An endless filter shows its first lines and stops when cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	b = svc.Stream(ctx, "repeat(1)")
+	b.WaitLines(100, time.Minute)
+	if b.Len() < 100 {
+		t.Fatalf("Len = %d after waiting for 100 lines", b.Len())
+	}
+	cancel()
+	<-b.Done()
+	if b.Err() == nil {
+		t.Fatal("cancelled stream should report an error")
+	}
+}
+
+func TestStreamLimits(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte(`null`), Config{Limits: Limits{Results: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := svc.Stream(context.Background(), "range(1e12)")
+	<-b.Done()
+	if b.Err() != nil || !b.Truncated() || len(b.Values()) != 5 {
+		t.Fatalf("got %d values (err %v, truncated %v), want 5 truncated", len(b.Values()), b.Err(), b.Truncated())
+	}
+
+	// Each "0123456789" takes 13 bytes with its quotes and newline
+	svc, err = NewServiceWithConfig([]byte(`null`), Config{Limits: Limits{Bytes: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = svc.Stream(context.Background(), `range(10) | "0123456789"`)
+	<-b.Done()
+	if len(b.Values()) != 2 || !b.Truncated() {
+		t.Fatalf("got %d values, want 2 truncated", len(b.Values()))
+	}
+
+	// The first output is kept however large
+	b = svc.Stream(context.Background(), `"x" * 100`)
+	<-b.Done()
+	if len(b.Values()) != 1 || b.Truncated() {
+		t.Fatalf("got %d values (truncated %v), want the one", len(b.Values()), b.Truncated())
+	}
+}
+
+func TestMeasureValue(t *testing.T) {
+	values, err := DecodeValues([]byte(`{"a":[1,2,{"b":null,"c":true}],"d":"text","e":{},"f":[false]} "s" 1.5`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []OutputOptions{{}, {Tab: true}, {Compact: true}, {YAML: true}} {
+		for _, v := range values {
+			text := FormatValue(v, opts)
+			lines, size := measureValue(v, opts)
+			if want := strings.Count(text, "\n") + 1; lines != want {
+				t.Errorf("%+v: lines(%s) = %d, want %d", opts, text, lines, want)
+			}
+			if size != len(text) {
+				t.Errorf("%+v: size(%s) = %d, want %d", opts, text, size, len(text))
+			}
+		}
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte(`{"items":[{"id":1},{"id":2}]}`), Config{Limits: Limits{Results: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []OutputOptions{{}, {YAML: true}, {Gron: true}, {Table: TableCSV}} {
+		svc.SetOutputOptions(opts)
+		var out strings.Builder
+		if err := svc.WriteResults(context.Background(), ".items[]", &out); err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		// The display limit does not apply
+		if want := svc.Execute(".items[]").Raw + "\n"; out.String() != want {
+			t.Errorf("%+v: wrote %q, want %q", opts, out.String(), want)
+		}
+
+		out.Reset()
+		if err := NewResultBuffer(svc.Execute(".items[]").Values, opts).Print(&out); err != nil {
+			t.Fatal(err)
+		}
+		if want := svc.Execute(".items[]").Raw + "\n"; out.String() != want {
+			t.Errorf("%+v: printed %q, want %q", opts, out.String(), want)
+		}
+	}
+
+	var out strings.Builder
+	if err := svc.WriteResults(context.Background(), "empty", &out); err != nil || out.Len() != 0 {
+		t.Fatalf("empty wrote %q (err %v)", out.String(), err)
+	}
+}
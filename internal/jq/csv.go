@@ -0,0 +1,76 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func decodeCSV(data []byte) ([]any, error) {
+	return decodeDelimited(data, ',', "CSV")
+}
+
+func decodeTSV(data []byte) ([]any, error) {
+	return decodeDelimited(data, '\t', "TSV")
+}
+
+// decodeDelimited reads a table whose first row names the columns and
+// returns it as one array of objects, one per row. Cells stay strings;
+// tonumber converts them where needed.
+func decodeDelimited(data []byte, comma rune, name string) ([]any, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	if comma == '\t' {
+		r.LazyQuotes = true
+	}
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return []any{[]any{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	header = columnNames(header)
+
+	rows := []any{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		row := make(map[string]any, len(header))
+		for i, col := range header {
+			row[col] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return []any{rows}, nil
+}
+
+// columnNames fills in blank and repeated header cells so every column
+// keeps its own key.
+func columnNames(header []string) []string {
+	names := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, h := range header {
+		base := h
+		if base == "" {
+			base = "column" + strconv.Itoa(i+1)
+		}
+		name := base
+		for n := 2; seen[name]; n++ {
+			name = base + "_" + strconv.Itoa(n)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
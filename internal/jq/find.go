@@ -0,0 +1,84 @@
+package jq
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+)
+
+// PathMatch is a place in a document found by FindPaths.
+type PathMatch struct {
+	Path  []any
+	Value any  // Value at Path
+	Key   bool // The last key of Path matched rather than the value
+}
+
+// FindPaths walks v depth first, in sorted key order, and returns the paths
+// whose object key or scalar value matches re. A positive limit stops the
+// walk after that many matches.
+func FindPaths(v any, re *regexp.Regexp, limit int) []PathMatch {
+	var matches []PathMatch
+	var walk func(path []any, v any) bool
+	walk = func(path []any, v any) bool {
+		switch val := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				child := append(path[:len(path):len(path)], k)
+				if re.MatchString(k) {
+					matches = append(matches, PathMatch{Path: child, Value: val[k], Key: true})
+					if limit > 0 && len(matches) >= limit {
+						return false
+					}
+					// A scalar under a matching key is listed once
+					if !isContainer(val[k]) {
+						continue
+					}
+				}
+				if !walk(child, val[k]) {
+					return false
+				}
+			}
+		case []any:
+			for i, item := range val {
+				if !walk(append(path[:len(path):len(path)], i), item) {
+					return false
+				}
+			}
+		default:
+			if re.MatchString(scalarText(v)) {
+				matches = append(matches, PathMatch{Path: path, Value: v})
+				if limit > 0 && len(matches) >= limit {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	walk(nil, v)
+	return matches
+}
+
+// scalarText is the text a scalar is matched against: strings unquoted,
+// everything else as JSON.
+func scalarText(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	}
+	return FormatValue(v, OutputOptions{Compact: true})
+}
+
+func isContainer(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package jq
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestFindPaths(t *testing.T) {
+	values, err := DecodeValues([]byte(`{
+		"users": [
+			{"id": 7, "email": "ann@example.com"},
+			{"id": 42, "email": "bob@example.com", "manager": 7}
+		],
+		"owner": {"email": "root@example.com"}
+	}`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	data := values[0]
+
+	paths := func(matches []PathMatch) []string {
+		out := make([]string, len(matches))
+		for i, m := range matches {
+			out[i] = FormatPath(m.Path)
+		}
+		return out
+	}
+
+	tests := []struct {
+		pattern string
+		limit   int
+		want    []string
+	}{
+		{"bob@", 0, []string{".users[1].email"}},
+		{"^7$", 0, []string{".users[0].id", ".users[1].manager"}},
+		// A matching key lists the key once, not its scalar value too
+		{"email", 0, []string{".owner.email", ".users[0].email", ".users[1].email"}},
+		{"example", 2, []string{".owner.email", ".users[0].email"}},
+		{"nobody", 0, []string{}},
+	}
+	for _, tt := range tests {
+		got := paths(FindPaths(data, regexp.MustCompile(tt.pattern), tt.limit))
+		if len(got) != len(tt.want) {
+			t.Errorf("FindPaths(%q) = %q, want %q", tt.pattern, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FindPaths(%q) = %q, want %q", tt.pattern, got, tt.want)
+				break
+			}
+		}
+	}
+
+	match := FindPaths(data, regexp.MustCompile("^owner$"), 0)
+	if len(match) != 1 || !match[0].Key {
+		t.Fatalf("key match = %+v, want one key match", match)
+	}
+}
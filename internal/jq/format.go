@@ -0,0 +1,181 @@
+package jq
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// OutputOptions controls how results are rendered to text
+type OutputOptions struct {
+	Raw     bool // Write strings without quotes (jq -r)
+	Compact bool // One line per result (jq -c)
+	Tab     bool // Indent with tabs instead of two spaces (jq --tab)
+	YAML    bool // Render results as YAML documents (yq -y)
+	Gron    bool // Render results as gron assignment lines
+	Schema  bool // Render a JSON Schema inferred from the results
+
+	// Table renders all results together as a CSV, TSV or Markdown table
+	// instead of one value after another.
+	Table TableFormat
+}
+
+// Indent returns the indentation unit, or "" for compact output.
+func (o OutputOptions) Indent() string {
+	switch {
+	case o.Compact:
+		return ""
+	case o.Tab:
+		return "\t"
+	default:
+		return "  "
+	}
+}
+
+// FormatValue renders a single jq value using opts.
+func FormatValue(v any, opts OutputOptions) string {
+	var buf bytes.Buffer
+	writeResult(&buf, v, opts)
+	return buf.String()
+}
+
+// wholeOutput reports whether opts lay out all results together rather
+// than one after another.
+func wholeOutput(opts OutputOptions) bool {
+	return opts.Table != "" || opts.Gron || opts.Schema
+}
+
+// resultWriter writes results one after another as they are produced, laid
+// out as formatResults does.
+type resultWriter struct {
+	w    *bufio.Writer
+	opts OutputOptions
+	buf  bytes.Buffer
+	n    int
+}
+
+func newResultWriter(w io.Writer, opts OutputOptions) *resultWriter {
+	return &resultWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (rw *resultWriter) write(v any) error {
+	rw.buf.Reset()
+	if rw.n > 0 {
+		rw.buf.WriteByte('\n')
+		if rw.opts.YAML && !rw.opts.Compact {
+			rw.buf.WriteString("---\n")
+		}
+	}
+	writeResult(&rw.buf, v, rw.opts)
+	rw.n++
+	_, err := rw.w.Write(rw.buf.Bytes())
+	return err
+}
+
+// close ends the output with a newline, if there was any, and flushes it.
+func (rw *resultWriter) close() error {
+	if rw.n > 0 {
+		if err := rw.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return rw.w.Flush()
+}
+
+func formatResults(results []any, opts OutputOptions) string {
+	var buf bytes.Buffer
+	for i, r := range results {
+		if i > 0 {
+			buf.WriteByte('\n')
+			// Block-style YAML results need document markers to stay apart
+			if opts.YAML && !opts.Compact {
+				buf.WriteString("---\n")
+			}
+		}
+		writeResult(&buf, r, opts)
+	}
+	return buf.String()
+}
+
+func writeResult(buf *bytes.Buffer, v any, opts OutputOptions) {
+	if s, ok := v.(string); ok && opts.Raw {
+		buf.WriteString(s)
+		return
+	}
+	if opts.YAML {
+		writeYAML(buf, v, opts.Compact)
+		return
+	}
+	writeJSON(buf, v, opts.Indent(), 0)
+}
+
+// writeJSON writes v as JSON. Object keys are always sorted since decoded
+// objects do not retain their source order.
+func writeJSON(buf *bytes.Buffer, v any, indent string, depth int) {
+	switch val := v.(type) {
+	case []any:
+		if len(val) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeNewline(buf, indent, depth+1)
+			writeJSON(buf, item, indent, depth+1)
+		}
+		writeNewline(buf, indent, depth)
+		buf.WriteByte(']')
+	case map[string]any:
+		if len(val) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeNewline(buf, indent, depth+1)
+			writeScalar(buf, k)
+			buf.WriteByte(':')
+			if indent != "" {
+				buf.WriteByte(' ')
+			}
+			writeJSON(buf, val[k], indent, depth+1)
+		}
+		writeNewline(buf, indent, depth)
+		buf.WriteByte('}')
+	default:
+		writeScalar(buf, val)
+	}
+}
+
+func writeNewline(buf *bytes.Buffer, indent string, depth int) {
+	if indent == "" {
+		return
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(strings.Repeat(indent, depth))
+}
+
+// writeScalar uses gojq's encoder so numbers and strings match jq's output.
+func writeScalar(buf *bytes.Buffer, v any) {
+	b, err := gojq.Marshal(v)
+	if err != nil {
+		buf.WriteString("null")
+		return
+	}
+	buf.Write(b)
+}
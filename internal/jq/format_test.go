@@ -0,0 +1,64 @@
+package jq
+
+import "testing"
+
+func TestOutputOptions(t *testing.T) {
+	svc, err := NewService([]byte(`{"name":"a<b>","tags":["x","y"],"empty":{},"n":1.5}`))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   OutputOptions
+		filter string
+		want   string
+	}{
+		{"default", OutputOptions{}, ".tags", "[\n  \"x\",\n  \"y\"\n]"},
+		{"compact", OutputOptions{Compact: true}, ".", `{"empty":{},"n":1.5,"name":"a<b>","tags":["x","y"]}`},
+		{"tab", OutputOptions{Tab: true}, ".tags", "[\n\t\"x\",\n\t\"y\"\n]"},
+		{"raw string", OutputOptions{Raw: true}, ".tags[]", "x\ny"},
+		{"raw non-string", OutputOptions{Raw: true, Compact: true}, ".tags", `["x","y"]`},
+		{"no html escaping", OutputOptions{}, ".name", `"a<b>"`},
+		{"empty object", OutputOptions{}, ".empty", "{}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.SetOutputOptions(tt.opts)
+			result := svc.Execute(tt.filter)
+			if result.Error != nil {
+				t.Fatalf("Execute failed: %v", result.Error)
+			}
+			if result.Raw != tt.want {
+				t.Errorf("Raw = %q, want %q", result.Raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	got := FormatValue(map[string]any{"b": 1, "a": []any{true, nil}}, OutputOptions{})
+	want := "{\n  \"a\": [\n    true,\n    null\n  ],\n  \"b\": 1\n}"
+	if got != want {
+		t.Errorf("FormatValue = %q, want %q", got, want)
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	tests := []struct {
+		path []any
+		want string
+	}{
+		{nil, "."},
+		{[]any{"items", 42, "metrics", "count"}, ".items[42].metrics.count"},
+		{[]any{0, "id"}, ".[0].id"},
+		{[]any{"first name", "_x1"}, `."first name"._x1`},
+		{[]any{"1st", "a\"b"}, `."1st"."a\"b"`},
+	}
+	for _, tt := range tests {
+		if got := FormatPath(tt.path); got != tt.want {
+			t.Errorf("FormatPath(%v) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
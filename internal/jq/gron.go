@@ -0,0 +1,280 @@
+package jq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GronStatement is one assignment of gron output: the value at Path, with
+// objects and arrays written empty and filled in by later statements.
+type GronStatement struct {
+	Path  []any
+	Value any
+}
+
+// String renders the statement as a line such as json.items[0].name = "x";
+func (s GronStatement) String() string {
+	var b strings.Builder
+	b.WriteString("json")
+	for _, p := range s.Path {
+		switch k := p.(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(k) + "]")
+		case string:
+			if isIdentifier(k) {
+				b.WriteString("." + k)
+			} else {
+				b.WriteString("[" + FormatValue(k, OutputOptions{}) + "]")
+			}
+		}
+	}
+	b.WriteString(" = ")
+	switch s.Value.(type) {
+	case map[string]any:
+		b.WriteString("{}")
+	case []any:
+		b.WriteString("[]")
+	default:
+		b.WriteString(FormatValue(s.Value, OutputOptions{Compact: true}))
+	}
+	b.WriteByte(';')
+	return b.String()
+}
+
+// GronStatements flattens results into assignments, parents before their
+// children and object keys in sorted order. Several results are numbered
+// like the elements of an array.
+func GronStatements(results []any) []GronStatement {
+	var root any = results
+	if len(results) == 1 {
+		root = results[0]
+	}
+
+	var out []GronStatement
+	var walk func(path []any, v any)
+	walk = func(path []any, v any) {
+		out = append(out, GronStatement{Path: path, Value: v})
+		switch val := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(append(path[:len(path):len(path)], k), val[k])
+			}
+		case []any:
+			for i, item := range val {
+				walk(append(path[:len(path):len(path)], i), item)
+			}
+		}
+	}
+	walk(nil, root)
+	return out
+}
+
+// Gron renders results as gron assignment lines.
+func Gron(results []any) string {
+	var b strings.Builder
+	for i, s := range GronStatements(results) {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// minUngronGaps is how many nulls Ungron may add to fill array gaps however
+// small its input. Beyond that each byte of input allows one more, so a
+// stray json[16000000] statement cannot allocate a huge array.
+const minUngronGaps = 1 << 16
+
+// ungronner rebuilds a value statement by statement.
+type ungronner struct {
+	root any
+	gaps int // Nulls still allowed to fill array gaps
+}
+
+// Ungron rebuilds a value from gron assignment lines. Statements may come
+// in any order or be a filtered subset; missing parents are created and
+// array gaps are filled with null. Blank lines are ignored.
+func Ungron(data []byte) (any, error) {
+	u := &ungronner{gaps: max(minUngronGaps, len(data))}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		stmt, err := parseGronStatement(text)
+		if err != nil {
+			return nil, fmt.Errorf("gron line %d: %w", line, err)
+		}
+		u.root, err = u.assign(u.root, stmt.Path, stmt.Value)
+		if err != nil {
+			return nil, fmt.Errorf("gron line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return u.root, nil
+}
+
+// UngronStatements rebuilds a value from statements made by GronStatements,
+// such as a filtered subset of them, without rendering and re-parsing them.
+// Their indices come from real arrays, so gaps are not limited.
+func UngronStatements(stmts []GronStatement) (any, error) {
+	u := &ungronner{gaps: math.MaxInt}
+	for _, stmt := range stmts {
+		// Containers are filled in by their own statements
+		value := stmt.Value
+		switch value.(type) {
+		case map[string]any:
+			value = map[string]any{}
+		case []any:
+			value = []any{}
+		}
+		var err error
+		if u.root, err = u.assign(u.root, stmt.Path, value); err != nil {
+			return nil, err
+		}
+	}
+	return u.root, nil
+}
+
+// decodeGron is the Decoder for gron input.
+func decodeGron(data []byte) ([]any, error) {
+	v, err := Ungron(data)
+	if err != nil {
+		return nil, err
+	}
+	return []any{v}, nil
+}
+
+// parseGronStatement parses json.path = value;
+func parseGronStatement(text string) (GronStatement, error) {
+	if !strings.HasPrefix(text, "json") {
+		return GronStatement{}, fmt.Errorf("statement must start with json")
+	}
+	rest := text[len("json"):]
+
+	var path []any
+	for {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			end := 1
+			for end < len(rest) && isSimpleIdentifierChar(rest[end]) {
+				end++
+			}
+			if end == 1 {
+				return GronStatement{}, fmt.Errorf("missing key after .")
+			}
+			path = append(path, rest[1:end])
+			rest = rest[end:]
+			continue
+		case strings.HasPrefix(rest, `["`):
+			dec := json.NewDecoder(strings.NewReader(rest[1:]))
+			var key string
+			if err := dec.Decode(&key); err != nil {
+				return GronStatement{}, fmt.Errorf("invalid key: %w", err)
+			}
+			after := rest[1+int(dec.InputOffset()):]
+			if !strings.HasPrefix(after, "]") {
+				return GronStatement{}, fmt.Errorf("missing ] after key")
+			}
+			path = append(path, key)
+			rest = after[1:]
+			continue
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return GronStatement{}, fmt.Errorf("missing ]")
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return GronStatement{}, fmt.Errorf("invalid index %q", rest[1:end])
+			}
+			path = append(path, idx)
+			rest = rest[end+1:]
+			continue
+		}
+		break
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return GronStatement{}, fmt.Errorf("expected = after the path")
+	}
+	rest = strings.TrimSuffix(strings.TrimSpace(rest[1:]), ";")
+	value, err := decodeSingle([]byte(rest))
+	if err != nil {
+		return GronStatement{}, fmt.Errorf("invalid value: %w", err)
+	}
+	return GronStatement{Path: path, Value: value}, nil
+}
+
+// assign sets path in cur to value and returns the updated value. An empty
+// container does not replace one already built by other statements.
+func (u *ungronner) assign(cur any, path []any, value any) (any, error) {
+	if len(path) == 0 {
+		switch v := value.(type) {
+		case map[string]any:
+			if existing, ok := cur.(map[string]any); ok && len(v) == 0 {
+				return existing, nil
+			}
+		case []any:
+			if existing, ok := cur.([]any); ok && len(v) == 0 {
+				return existing, nil
+			}
+		}
+		return value, nil
+	}
+
+	switch k := path[0].(type) {
+	case string:
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			if cur != nil {
+				return nil, fmt.Errorf("cannot set key %q on %s", k, typeName(cur))
+			}
+			obj = map[string]any{}
+		}
+		child, err := u.assign(obj[k], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = child
+		return obj, nil
+	case int:
+		arr, ok := cur.([]any)
+		if !ok && cur != nil {
+			return nil, fmt.Errorf("cannot set index %d on %s", k, typeName(cur))
+		}
+		if gap := k - len(arr); gap >= 0 {
+			if gap > u.gaps {
+				return nil, fmt.Errorf("index %d is too far past the end of the array", k)
+			}
+			u.gaps -= gap
+			arr = append(arr, make([]any, gap+1)...)
+		}
+		child, err := u.assign(arr[k], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		arr[k] = child
+		return arr, nil
+	}
+	return nil, fmt.Errorf("invalid path element %v", path[0])
+}
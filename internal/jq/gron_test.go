@@ -0,0 +1,100 @@
+package jq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGron(t *testing.T) {
+	values, err := DecodeValues([]byte(`{"items":[{"name":"x","tags":[]}],"first name":"Ann","n":1.50}`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`json = {};`,
+		`json["first name"] = "Ann";`,
+		`json.items = [];`,
+		`json.items[0] = {};`,
+		`json.items[0].name = "x";`,
+		`json.items[0].tags = [];`,
+		`json.n = 1.50;`,
+	}, "\n")
+	if got := Gron(values); got != want {
+		t.Fatalf("Gron =\n%s\nwant\n%s", got, want)
+	}
+
+	// Ungron restores the document exactly
+	back, err := Ungron([]byte(want))
+	if err != nil {
+		t.Fatalf("Ungron failed: %v", err)
+	}
+	if got, orig := FormatValue(back, OutputOptions{Compact: true}), FormatValue(values[0], OutputOptions{Compact: true}); got != orig {
+		t.Fatalf("round trip = %s, want %s", got, orig)
+	}
+
+	if got := Gron([]any{"a", "b"}); got != "json = [];\njson[0] = \"a\";\njson[1] = \"b\";" {
+		t.Fatalf("Gron of several results = %q", got)
+	}
+}
+
+func TestUngron(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"filtered lines", "json.items[1].name = \"b\";\n", `{"items":[null,{"name":"b"}]}`},
+		{"any order", "json.a.b = 1;\njson = {};\njson.a = {};", `{"a":{"b":1}}`},
+		{"quoted key", `json["a;b = c"] = "x = y;";`, `{"a;b = c":"x = y;"}`},
+		{"no semicolon", "json.ok = true", `{"ok":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Ungron([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Ungron failed: %v", err)
+			}
+			if got := FormatValue(v, OutputOptions{Compact: true}); got != tt.want {
+				t.Fatalf("Ungron = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"items = 1;", "json.a = ;", "json.a = 1;\njson.a.b = 2;", "json[x] = 1;", "json[16000000] = 1;", "json.a[70000] = 1;\njson.b[70000] = 1;"} {
+		if _, err := Ungron([]byte(bad)); err == nil {
+			t.Errorf("Ungron(%q) should fail", bad)
+		}
+	}
+}
+
+func TestUngronStatements(t *testing.T) {
+	data := map[string]any{"a": []any{"x", map[string]any{"b": "y"}}, "c": true}
+	var kept []GronStatement
+	for _, stmt := range GronStatements([]any{data}) {
+		if len(stmt.Path) != 1 || stmt.Path[0] != "c" {
+			kept = append(kept, stmt)
+		}
+	}
+	v, err := UngronStatements(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := FormatValue(v, OutputOptions{Compact: true}), `{"a":["x",{"b":"y"}]}`; got != want {
+		t.Fatalf("UngronStatements = %s, want %s", got, want)
+	}
+	if _, ok := data["c"]; !ok {
+		t.Fatal("UngronStatements changed the values the statements came from")
+	}
+}
+
+func TestGronInputFormat(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte("json.user.id = 7;\n"), Config{InputFormat: FormatGron})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	svc.SetOutputOptions(OutputOptions{Gron: true})
+	if got := svc.Execute(".user").Raw; got != "json = {};\njson.id = 7;" {
+		t.Fatalf("gron output = %q", got)
+	}
+}
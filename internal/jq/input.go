@@ -0,0 +1,158 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InputFormat names the encoding of the input document
+type InputFormat string
+
+const (
+	FormatJSON InputFormat = "json"
+	FormatYAML InputFormat = "yaml"
+	FormatTOML InputFormat = "toml"
+	FormatCSV  InputFormat = "csv"
+	FormatTSV  InputFormat = "tsv"
+	FormatXML  InputFormat = "xml"
+	FormatGron InputFormat = "gron"
+)
+
+// Decoder converts raw input into the values filters run against. Values
+// must be ones gojq understands: nil, bool, string, json.Number, float64,
+// []any and map[string]any.
+type Decoder func(data []byte) ([]any, error)
+
+type inputDecoder struct {
+	decode     Decoder
+	extensions []string
+}
+
+var decoders = map[InputFormat]inputDecoder{}
+
+// RegisterDecoder makes format available to ParseInputFormat and, for the
+// given extensions (with the leading dot), to FormatForPath.
+func RegisterDecoder(format InputFormat, decode Decoder, extensions ...string) {
+	decoders[format] = inputDecoder{decode: decode, extensions: extensions}
+}
+
+func init() {
+	RegisterDecoder(FormatJSON, decodeValues, ".json")
+	RegisterDecoder(FormatYAML, decodeYAML, ".yaml", ".yml")
+	RegisterDecoder(FormatTOML, decodeTOML, ".toml")
+	RegisterDecoder(FormatCSV, decodeCSV, ".csv")
+	RegisterDecoder(FormatTSV, decodeTSV, ".tsv", ".tab")
+	RegisterDecoder(FormatXML, decodeXML, ".xml")
+	RegisterDecoder(FormatGron, decodeGron, ".gron")
+}
+
+// formatAliases maps alternative names accepted by --input-format.
+var formatAliases = map[string]InputFormat{
+	"ndjson": FormatJSON,
+	"jsonl":  FormatJSON,
+	"yml":    FormatYAML,
+}
+
+// InputFormats lists the registered format names in order.
+func InputFormats() []string {
+	names := make([]string, 0, len(decoders))
+	for format := range decoders {
+		names = append(names, string(format))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseInputFormat validates a format name given on the command line.
+func ParseInputFormat(name string) (InputFormat, error) {
+	name = strings.ToLower(name)
+	if format, ok := formatAliases[name]; ok {
+		return format, nil
+	}
+	if _, ok := decoders[InputFormat(name)]; ok {
+		return InputFormat(name), nil
+	}
+	return "", fmt.Errorf("unknown input format %q (want one of %s)", name, strings.Join(InputFormats(), ", "))
+}
+
+// FormatForPath guesses the input format from a file extension, defaulting
+// to JSON.
+func FormatForPath(path string) InputFormat {
+	ext := strings.ToLower(filepath.Ext(path))
+	for format, d := range decoders {
+		for _, e := range d.extensions {
+			if e == ext {
+				return format
+			}
+		}
+	}
+	return FormatJSON
+}
+
+// decodeInput splits data into input values according to cfg.
+func decodeInput(data []byte, cfg Config) ([]any, error) {
+	format := cfg.InputFormat
+	if format == "" || format == FormatJSON {
+		if cfg.Stream {
+			return decodeLines(data)
+		}
+		return decodeValues(data)
+	}
+	d, ok := decoders[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	return d.decode(data)
+}
+
+// normalizeValue converts values from decoders built on reflection into the
+// types gojq accepts. Integers become exact json.Numbers and times become
+// the strings they were written as.
+func normalizeValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeValue(item)
+		}
+		return val
+	case []map[string]any:
+		arr := make([]any, len(val))
+		for i, item := range val {
+			arr[i] = normalizeValue(item)
+		}
+		return arr
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeValue(item)
+		}
+		return val
+	case int64:
+		return json.Number(strconv.FormatInt(val, 10))
+	case int:
+		return json.Number(strconv.Itoa(val))
+	case uint64:
+		return json.Number(strconv.FormatUint(val, 10))
+	case time.Time:
+		return formatTime(val)
+	}
+	return v
+}
+
+// formatTime renders decoded dates and times, keeping TOML's local
+// date, time and datetime values in their original shape.
+func formatTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format(time.DateOnly)
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,135 @@
+package jq
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInputDecoders(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		filter  string
+		want    string
+	}{
+		{"toml table", "Cargo.toml", ".package | [.name, .version, .edition]", `["gijq-fixture","0.3.1","2021"]`},
+		{"toml inline table", "Cargo.toml", ".dependencies.serde.features", `["derive"]`},
+		{"toml array of tables", "Cargo.toml", "[.bin[].name]", `["fixture","helper"]`},
+		{"toml exact ints", "Cargo.toml", ".profile.release | [.\"opt-level\", .id, .lto]", `[3,9223372036854775807,true]`},
+		{"toml dates", "Cargo.toml", ".package | [.released, .build]", `["2024-05-01","1979-05-27T07:32:00Z"]`},
+		{"csv rows", "people.csv", "length", "3"},
+		{"csv quoted cells", "people.csv", ".[1] | [.id, .name, .city]", `["2","Hopper, Grace","New York"]`},
+		{"csv empty cell", "people.csv", ".[2].email", `""`},
+		{"xml text elements", "feed.xml", ".rss.channel.title", `"Release notes"`},
+		{"xml attributes", "feed.xml", ".rss[\"@version\"]", `"2.0"`},
+		{"xml repeated children", "feed.xml", "[.rss.channel.item[].title]", `["v1.2.0","v1.1.0"]`},
+		{"xml single and repeated", "feed.xml", "[.rss.channel.item[].category]", `["release",["release","security"]]`},
+		{"xml text with attributes", "feed.xml", ".rss.channel.item[0].guid", `{"#text":"1234567890123456789","@isPermaLink":"false"}`},
+		{"xml empty element", "feed.xml", ".rss.channel.link", `{"@href":"https://example.com/feed.xml","@rel":"self"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile("../../testdata/" + tt.fixture)
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			svc, err := NewServiceWithConfig(data, Config{
+				InputFormat: FormatForPath(tt.fixture),
+				Output:      OutputOptions{Compact: true},
+			})
+			if err != nil {
+				t.Fatalf("NewServiceWithConfig failed: %v", err)
+			}
+			result := svc.Execute(tt.filter)
+			if result.Error != nil {
+				t.Fatalf("Execute failed: %v", result.Error)
+			}
+			if result.Raw != tt.want {
+				t.Errorf("Raw = %q, want %q", result.Raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputDecoderErrors(t *testing.T) {
+	tests := []struct {
+		format InputFormat
+		input  string
+		want   string
+	}{
+		{FormatTOML, "a = ", "invalid TOML"},
+		{FormatCSV, "a,b\n1,2,3\n", "invalid CSV"},
+		{FormatXML, "<a><b></a>", "invalid XML"},
+		{FormatXML, "just text", "invalid XML: no root element"},
+	}
+	for _, tt := range tests {
+		_, err := NewServiceWithConfig([]byte(tt.input), Config{InputFormat: tt.format})
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s %q: err = %v, want prefix %q", tt.format, tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestCSVColumnNames(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte("a,,a\n1,2,3\n"), Config{InputFormat: FormatCSV, Output: OutputOptions{Compact: true}})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	if got, want := svc.Execute(".[0]").Raw, `{"a":"1","a_2":"3","column2":"2"}`; got != want {
+		t.Errorf("Raw = %q, want %q", got, want)
+	}
+}
+
+func TestParseInputFormat(t *testing.T) {
+	for name, want := range map[string]InputFormat{
+		"json": FormatJSON, "JSONL": FormatJSON, "yml": FormatYAML,
+		"toml": FormatTOML, "csv": FormatCSV, "tsv": FormatTSV, "xml": FormatXML,
+	} {
+		got, err := ParseInputFormat(name)
+		if err != nil || got != want {
+			t.Errorf("ParseInputFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseInputFormat("ini"); err == nil {
+		t.Error("ParseInputFormat(ini) succeeded, want error")
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	for path, want := range map[string]InputFormat{
+		"deploy.yaml":    FormatYAML,
+		"CI.YML":         FormatYAML,
+		"Cargo.toml":     FormatTOML,
+		"export.csv":     FormatCSV,
+		"export.tsv":     FormatTSV,
+		"feed.xml":       FormatXML,
+		"data.json":      FormatJSON,
+		"service.ndjson": FormatJSON,
+		"<stdin>":        FormatJSON,
+	} {
+		if got := FormatForPath(path); got != want {
+			t.Errorf("FormatForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	const format InputFormat = "lines"
+	RegisterDecoder(format, func(data []byte) ([]any, error) {
+		return []any{string(data)}, nil
+	}, ".lines")
+	defer delete(decoders, format)
+
+	if got := FormatForPath("notes.lines"); got != format {
+		t.Fatalf("FormatForPath = %q, want %q", got, format)
+	}
+	svc, err := NewServiceWithConfig([]byte("hi"), Config{InputFormat: format})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	if got := svc.Execute(".").Raw; got != `"hi"` {
+		t.Errorf("Raw = %q, want %q", got, `"hi"`)
+	}
+}
@@ -0,0 +1,588 @@
+package jq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsonReadSize is how much input the JSON decoder reads at a time.
+const jsonReadSize = 256 << 10
+
+// jsonMaxDepth matches the nesting limit of encoding/json.
+const jsonMaxDepth = 10000
+
+// jsonDecoder parses JSON values as their bytes are read, so a document is
+// never held in memory next to the values built from it. Values match
+// encoding/json with UseNumber: numbers stay exact as json.Number.
+type jsonDecoder struct {
+	r    io.Reader // nil once the input is exhausted
+	err  error     // Read error other than io.EOF
+	buf  []byte
+	pos  int
+	off  int64  // Input offset of buf[0]
+	text []byte // Strings and numbers that span reads
+	keys map[string]string
+}
+
+// jsonKeyCache bounds the object keys shared between values. Arrays of
+// records repeat the same few keys, so sharing them saves an allocation
+// per field.
+const jsonKeyCache = 4096
+
+func newJSONDecoder(r io.Reader) *jsonDecoder {
+	return &jsonDecoder{r: r, buf: make([]byte, 0, jsonReadSize), keys: map[string]string{}}
+}
+
+// newJSONBytesDecoder decodes data in place, without copying it.
+func newJSONBytesDecoder(data []byte) *jsonDecoder {
+	return &jsonDecoder{buf: data, keys: map[string]string{}}
+}
+
+// reset decodes data next, in place.
+func (d *jsonDecoder) reset(data []byte) {
+	d.r, d.err, d.buf, d.pos, d.off = nil, nil, data, 0, 0
+}
+
+// ensure makes at least n bytes available from pos, reporting false if the
+// input ends first.
+func (d *jsonDecoder) ensure(n int) bool {
+	if len(d.buf)-d.pos >= n {
+		return true
+	}
+	if d.r == nil {
+		return false
+	}
+	if d.pos > 0 {
+		d.off += int64(d.pos)
+		d.buf = d.buf[:copy(d.buf[:cap(d.buf)], d.buf[d.pos:])]
+		d.pos = 0
+	}
+	for len(d.buf) < n {
+		read, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+read]
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				d.err = err
+			}
+			d.r = nil
+			return len(d.buf) >= n
+		}
+	}
+	return true
+}
+
+// next returns the next byte without consuming it.
+func (d *jsonDecoder) next() (byte, bool) {
+	if d.pos >= len(d.buf) && !d.ensure(1) {
+		return 0, false
+	}
+	return d.buf[d.pos], true
+}
+
+// skipSpace consumes whitespace and returns the byte after it.
+func (d *jsonDecoder) skipSpace() (byte, bool) {
+	for {
+		for d.pos < len(d.buf) {
+			switch c := d.buf[d.pos]; c {
+			case ' ', '\t', '\n', '\r':
+				d.pos++
+			default:
+				return c, true
+			}
+		}
+		if !d.ensure(1) {
+			return 0, false
+		}
+	}
+}
+
+// syntaxError reports a problem at the current offset, or the read error
+// that cut the input short.
+func (d *jsonDecoder) syntaxError(format string, args ...any) error {
+	if d.err != nil {
+		return d.err
+	}
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), d.off+int64(d.pos))
+}
+
+func (d *jsonDecoder) unexpectedEnd() error {
+	if d.err != nil {
+		return d.err
+	}
+	return errors.New("unexpected end of JSON input")
+}
+
+// invalid reports the byte at pos.
+func (d *jsonDecoder) invalid(context string) error {
+	c, ok := d.next()
+	if !ok {
+		return d.unexpectedEnd()
+	}
+	return d.syntaxError("invalid character %s %s", quoteChar(c), context)
+}
+
+// quoteChar formats c the way encoding/json does in its errors.
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(rune(c)))
+	return "'" + s[1:len(s)-1] + "'"
+}
+
+// value decodes the value starting at the next non-space byte.
+func (d *jsonDecoder) value(depth int) (any, error) {
+	c, ok := d.skipSpace()
+	if !ok {
+		return nil, d.unexpectedEnd()
+	}
+	switch {
+	case c == '{':
+		return d.object(depth + 1)
+	case c == '[':
+		return d.array(depth + 1)
+	case c == '"':
+		d.pos++
+		return d.string()
+	case c == '-' || ('0' <= c && c <= '9'):
+		return d.number()
+	case c == 't':
+		return true, d.literal("true")
+	case c == 'f':
+		return false, d.literal("false")
+	case c == 'n':
+		return nil, d.literal("null")
+	}
+	return nil, d.invalid("looking for beginning of value")
+}
+
+func (d *jsonDecoder) object(depth int) (any, error) {
+	if depth > jsonMaxDepth {
+		return nil, errors.New("exceeded max depth")
+	}
+	d.pos++ // {
+	obj := map[string]any{}
+	c, ok := d.skipSpace()
+	if ok && c == '}' {
+		d.pos++
+		return obj, nil
+	}
+	for {
+		if !ok {
+			return nil, d.unexpectedEnd()
+		}
+		if c != '"' {
+			return nil, d.invalid("looking for beginning of object key string")
+		}
+		d.pos++
+		key, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		if c, ok = d.skipSpace(); !ok || c != ':' {
+			return nil, d.invalid("after object key")
+		}
+		d.pos++
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+
+		c, ok = d.skipSpace()
+		switch {
+		case ok && c == ',':
+			d.pos++
+			c, ok = d.skipSpace()
+		case ok && c == '}':
+			d.pos++
+			return obj, nil
+		default:
+			return nil, d.invalid("after object key:value pair")
+		}
+	}
+}
+
+func (d *jsonDecoder) array(depth int) (any, error) {
+	if depth > jsonMaxDepth {
+		return nil, errors.New("exceeded max depth")
+	}
+	d.pos++ // [
+	arr := []any{}
+	if c, ok := d.skipSpace(); ok && c == ']' {
+		d.pos++
+		return arr, nil
+	}
+	for {
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		c, ok := d.skipSpace()
+		switch {
+		case ok && c == ',':
+			d.pos++
+		case ok && c == ']':
+			d.pos++
+			return arr, nil
+		default:
+			return nil, d.invalid("after array element")
+		}
+	}
+}
+
+func (d *jsonDecoder) literal(word string) error {
+	for i := 0; i < len(word); i++ {
+		c, ok := d.next()
+		if !ok {
+			return d.unexpectedEnd()
+		}
+		if c != word[i] {
+			return d.invalid("in literal " + word + " (expecting " + quoteChar(word[i]) + ")")
+		}
+		d.pos++
+	}
+	return nil
+}
+
+// key decodes an object key, sharing the string with earlier equal keys.
+func (d *jsonDecoder) key() (string, error) {
+	raw, err := d.stringBytes()
+	if err != nil {
+		return "", err
+	}
+	if key, ok := d.keys[string(raw)]; ok {
+		return key, nil
+	}
+	key := string(raw)
+	if len(d.keys) < jsonKeyCache {
+		d.keys[key] = key
+	}
+	return key, nil
+}
+
+func (d *jsonDecoder) string() (any, error) {
+	raw, err := d.stringBytes()
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// stringBytes decodes a string whose opening quote has been consumed. The
+// bytes are only valid until the decoder reads again.
+func (d *jsonDecoder) stringBytes() ([]byte, error) {
+	start, ascii := d.pos, true
+	for d.pos < len(d.buf) {
+		c := d.buf[d.pos]
+		if c == '"' {
+			if raw := d.buf[start:d.pos]; ascii || utf8.Valid(raw) {
+				d.pos++
+				return raw, nil
+			}
+			break
+		}
+		if c == '\\' || c < 0x20 {
+			break
+		}
+		ascii = ascii && c < utf8.RuneSelf
+		d.pos++
+	}
+	if !ascii {
+		d.pos = start // Invalid UTF-8 is replaced rune by rune below
+	}
+
+	// Escapes, control characters, UTF-8 or the end of the buffer
+	d.text = append(d.text[:0], d.buf[start:d.pos]...)
+	for {
+		c, ok := d.next()
+		if !ok {
+			return nil, d.unexpectedEnd()
+		}
+		switch {
+		case c == '"':
+			d.pos++
+			return d.text, nil
+		case c == '\\':
+			d.pos++
+			if err := d.escape(); err != nil {
+				return nil, err
+			}
+		case c < 0x20:
+			return nil, d.invalid("in string literal")
+		case c < utf8.RuneSelf:
+			run := d.pos + 1
+			for run < len(d.buf) && d.buf[run] >= 0x20 && d.buf[run] < utf8.RuneSelf && d.buf[run] != '"' && d.buf[run] != '\\' {
+				run++
+			}
+			d.text = append(d.text, d.buf[d.pos:run]...)
+			d.pos = run
+		default:
+			d.ensure(utf8.UTFMax)
+			r, size := utf8.DecodeRune(d.buf[d.pos:])
+			if r == utf8.RuneError && size == 1 {
+				d.text = utf8.AppendRune(d.text, utf8.RuneError)
+			} else {
+				d.text = append(d.text, d.buf[d.pos:d.pos+size]...)
+			}
+			d.pos += size
+		}
+	}
+}
+
+// escape decodes the escape sequence after a backslash.
+func (d *jsonDecoder) escape() error {
+	c, ok := d.next()
+	if !ok {
+		return d.unexpectedEnd()
+	}
+	switch c {
+	case '"', '\\', '/':
+		d.text = append(d.text, c)
+	case 'b':
+		d.text = append(d.text, '\b')
+	case 'f':
+		d.text = append(d.text, '\f')
+	case 'n':
+		d.text = append(d.text, '\n')
+	case 'r':
+		d.text = append(d.text, '\r')
+	case 't':
+		d.text = append(d.text, '\t')
+	case 'u':
+		d.pos++
+		r, err := d.hex4()
+		if err != nil {
+			return err
+		}
+		if utf16.IsSurrogate(r) {
+			// A high surrogate combines with a following low one; anything
+			// else leaves it unpaired, which becomes U+FFFD.
+			r2 := utf8.RuneError
+			if d.ensure(6) && d.buf[d.pos] == '\\' && d.buf[d.pos+1] == 'u' {
+				if low, ok := parseHex4(d.buf[d.pos+2 : d.pos+6]); ok {
+					if combined := utf16.DecodeRune(r, low); combined != utf8.RuneError {
+						d.pos += 6
+						r2 = combined
+					}
+				}
+			}
+			r = r2
+		}
+		d.text = utf8.AppendRune(d.text, r)
+		return nil
+	default:
+		return d.invalid("in string escape code")
+	}
+	d.pos++
+	return nil
+}
+
+func (d *jsonDecoder) hex4() (rune, error) {
+	for i := 0; i < 4; i++ {
+		c, ok := d.next()
+		if !ok {
+			return 0, d.unexpectedEnd()
+		}
+		if _, isHex := parseHex4([]byte{c}); !isHex {
+			return 0, d.invalid("in \\u hexadecimal character escape")
+		}
+		d.text = append(d.text, c)
+		d.pos++
+	}
+	r, _ := parseHex4(d.text[len(d.text)-4:])
+	d.text = d.text[:len(d.text)-4]
+	return r, nil
+}
+
+func parseHex4(b []byte) (rune, bool) {
+	var r rune
+	for _, c := range b {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+// number decodes a number, ending where the JSON grammar does.
+func (d *jsonDecoder) number() (any, error) {
+	start := d.pos
+	d.text = d.text[:0]
+	spans := false // The number continues past the buffer start read
+	take := func() (byte, bool) {
+		if d.pos >= len(d.buf) {
+			if !spans {
+				d.text = append(d.text, d.buf[start:d.pos]...)
+				spans = true
+			}
+			if !d.ensure(1) {
+				return 0, false
+			}
+		}
+		return d.buf[d.pos], true
+	}
+	accept := func(c byte) {
+		if spans {
+			d.text = append(d.text, c)
+		}
+		d.pos++
+	}
+	digits := func(context string) error {
+		c, ok := take()
+		if !ok || c < '0' || c > '9' {
+			if !ok {
+				return d.unexpectedEnd()
+			}
+			return d.invalid(context)
+		}
+		for ok && '0' <= c && c <= '9' {
+			accept(c)
+			c, ok = take()
+		}
+		return nil
+	}
+
+	c, _ := take()
+	if c == '-' {
+		accept(c)
+		if c, _ = take(); c == '0' {
+			accept(c)
+		} else if err := digits("in numeric literal"); err != nil {
+			return nil, err
+		}
+	} else if c == '0' {
+		accept(c)
+	} else if err := digits("in numeric literal"); err != nil {
+		return nil, err
+	}
+	if c, ok := take(); ok && c == '.' {
+		accept(c)
+		if err := digits("after decimal point in numeric literal"); err != nil {
+			return nil, err
+		}
+	}
+	if c, ok := take(); ok && (c == 'e' || c == 'E') {
+		accept(c)
+		if c, ok := take(); ok && (c == '+' || c == '-') {
+			accept(c)
+		}
+		if err := digits("in exponent of numeric literal"); err != nil {
+			return nil, err
+		}
+	}
+	if spans {
+		return json.Number(d.text), nil
+	}
+	return json.Number(d.buf[start:d.pos]), nil
+}
+
+// decodeValues decodes a sequence of whitespace-separated JSON values.
+// Numbers are kept as json.Number, which gojq understands natively, so large
+// integers survive filtering and output without rounding through float64.
+func decodeValues(jsonData []byte) ([]any, error) {
+	return decodeJSON(newJSONBytesDecoder(jsonData))
+}
+
+// readValues decodes a sequence of whitespace-separated JSON values as r is
+// read.
+func readValues(r io.Reader) ([]any, error) {
+	return decodeJSON(newJSONDecoder(r))
+}
+
+func decodeJSON(d *jsonDecoder) ([]any, error) {
+	var inputs []any
+	for {
+		if _, ok := d.skipSpace(); !ok {
+			if d.err != nil {
+				return nil, fmt.Errorf("failed to read input: %w", d.err)
+			}
+			return inputs, nil
+		}
+		v, err := d.value(0)
+		if d.err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", d.err)
+		}
+		if err != nil {
+			if len(inputs) > 0 {
+				return nil, fmt.Errorf("invalid JSON in value %d: %w", len(inputs)+1, err)
+			}
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		inputs = append(inputs, v)
+	}
+}
+
+// decodeSingle decodes exactly one JSON value, keeping numbers exact.
+func decodeSingle(text []byte) (any, error) {
+	return newJSONBytesDecoder(text).single()
+}
+
+// single decodes the only value left in the input.
+func (d *jsonDecoder) single() (any, error) {
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.skipSpace(); ok {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// decodeLines decodes JSON Lines input, one value per non-blank line.
+func decodeLines(jsonData []byte) ([]any, error) {
+	return readLines(bytes.NewReader(jsonData))
+}
+
+// readLines decodes JSON Lines as r is read.
+func readLines(r io.Reader) ([]any, error) {
+	br := bufio.NewReaderSize(r, jsonReadSize)
+	d := newJSONBytesDecoder(nil)
+	var long []byte // A line longer than the read buffer
+	var inputs []any
+	for line := 1; ; line++ {
+		text, err := br.ReadSlice('\n')
+		for errors.Is(err, bufio.ErrBufferFull) {
+			long = append(long, text...)
+			text, err = br.ReadSlice('\n')
+		}
+		if long != nil {
+			text, long = append(long, text...), nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		if text = bytes.TrimSpace(text); len(text) > 0 {
+			d.reset(text)
+			v, decodeErr := d.single()
+			if decodeErr != nil {
+				return nil, fmt.Errorf("invalid JSON on line %d: %w", line, decodeErr)
+			}
+			inputs = append(inputs, v)
+		}
+		if err != nil {
+			return inputs, nil
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// stdValues decodes like the encoding/json based decoder this replaced.
+func stdValues(data []byte) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values []any
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+func TestReadValuesMatchesEncodingJSON(t *testing.T) {
+	inputs := []string{
+		`{"a":[1,2,{"b":null,"c":true}],"d":"text","e":{},"f":[false]}`,
+		`1 2 3`,
+		"[]\n{}\n\"\"",
+		`{"a":1}{"a":2}`,
+		`-0 0.5 -1.25e+10 3E-2 123456789012345678901234567890`,
+		`"esc \" \\ \/ \b \f \n \r \t é € 😀"`,
+		`"lone \ud800 surrogate \udc00 \ud800A"`,
+		"\"caf\xc3\xa9 \xe2\x82\xac \xf0\x9f\x98\x80\"",
+		"\"bad \xff\xfe utf8 \xe2\x82\"",
+		`{"dup":1,"dup":2}`,
+		`  [ 1 , [ 2 , [ 3 ] ] ]  `,
+		"\t\r\n",
+		`{"a":1,}`,
+		`[1,]`,
+		`[1 2]`,
+		`{"a" 1}`,
+		`{1:2}`,
+		`"unterminated`,
+		"\"ctrl \x01\"",
+		`"bad \x escape"`,
+		`"\u12G4"`,
+		`tru`,
+		`nul1`,
+		`-`,
+		`1.`,
+		`1e+`,
+		`[`,
+		`{"a":`,
+		`}`,
+	}
+	for _, in := range inputs {
+		want, wantErr := stdValues([]byte(in))
+		for name, decode := range map[string]func() ([]any, error){
+			"bytes":    func() ([]any, error) { return decodeValues([]byte(in)) },
+			"one byte": func() ([]any, error) { return readValues(iotest.OneByteReader(strings.NewReader(in))) },
+		} {
+			got, err := decode()
+			if (err != nil) != (wantErr != nil) {
+				t.Errorf("%s %q: error %v, encoding/json %v", name, in, err, wantErr)
+				continue
+			}
+			if err == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %q = %#v, want %#v", name, in, got, want)
+			}
+		}
+	}
+}
+
+func TestReadValuesErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `{"a":1,}`, want: `invalid JSON: invalid character '}' looking for beginning of object key string at offset 7`},
+		{in: `1 [2 3]`, want: `invalid JSON in value 2: invalid character '3' after array element at offset 5`},
+		{in: `{"a":`, want: `invalid JSON: unexpected end of JSON input`},
+		{in: strings.Repeat("[", jsonMaxDepth+1), want: `invalid JSON: exceeded max depth`},
+	}
+	for _, tt := range tests {
+		_, err := readValues(strings.NewReader(tt.in))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("readValues(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+
+	_, err := readValues(io.MultiReader(strings.NewReader(`[1,`), iotest.ErrReader(errors.New("disk gone"))))
+	if err == nil || err.Error() != "failed to read input: disk gone" {
+		t.Fatalf("read error = %v", err)
+	}
+}
+
+func TestReadValuesSpansReads(t *testing.T) {
+	// Values straddle the decoder's read buffer
+	var b strings.Builder
+	b.WriteString("[")
+	for b.Len() < 3*jsonReadSize {
+		b.WriteString(`{"name":"caf` + "é" + `","n":-12.5e3,"s":"a\"b"},`)
+	}
+	b.WriteString(`"` + strings.Repeat("x", jsonReadSize+10) + `"]`)
+	want, err := stdValues([]byte(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readValues(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("values decoded across reads differ from encoding/json")
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	long := `{"s":"` + strings.Repeat("y", 2*jsonReadSize) + `"}`
+	values, err := readLines(strings.NewReader("{\"a\":1}\n\n  [2]  \r\n" + long))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 || len(values[2].(map[string]any)["s"].(string)) != 2*jsonReadSize {
+		t.Fatalf("values = %d", len(values))
+	}
+
+	_, err = readLines(strings.NewReader("1\n2 3\n"))
+	if err == nil || err.Error() != "invalid JSON on line 2: unexpected data after JSON value" {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	svc := NewLoadingService(Config{})
+	if svc.Loaded() || svc.Data() != nil {
+		t.Fatal("service should start without input")
+	}
+	if _, err := svc.KeysAt("."); !errors.Is(err, ErrNotLoaded) {
+		t.Fatalf("KeysAt before loading: %v", err)
+	}
+
+	done := make(chan Result)
+	go func() { done <- svc.Execute(".a") }()
+
+	input := `{"a":[1,2]}`
+	if err := svc.Load(strings.NewReader(input), int64(len(input))); err != nil {
+		t.Fatal(err)
+	}
+	if result := <-done; result.Error != nil || result.Raw != "[\n  1,\n  2\n]" {
+		t.Fatalf("filter run before loading = %q, %v", result.Raw, result.Error)
+	}
+	if read, total := svc.LoadProgress(); read != int64(len(input)) || total != int64(len(input)) {
+		t.Fatalf("progress = %d of %d", read, total)
+	}
+
+	svc = NewLoadingService(Config{})
+	if err := svc.Load(strings.NewReader("  "), 2); err == nil || svc.WaitLoaded() == nil {
+		t.Fatal("blank input should fail to load")
+	}
+	if result := svc.Execute("."); result.Error == nil {
+		t.Fatal("filters should report the load error")
+	}
+}
@@ -0,0 +1,149 @@
+package jq
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/itchyny/gojq"
+)
+
+// homeLibrary is always searched, so definitions in a ~/.jq file are
+// available to every filter and modules under a ~/.jq directory can be
+// imported, like jq.
+const homeLibrary = "~/.jq"
+
+// importRe matches module directives in a filter. Data imports ("as $name")
+// are skipped since they bind variables rather than functions.
+var importRe = regexp.MustCompile(`\b(import|include)\s+"([^"]+)"(?:\s+as\s+([A-Za-z_][A-Za-z0-9_]*))?`)
+
+// resolveModulePaths expands ~ in the search paths and appends ~/.jq.
+func resolveModulePaths(paths []string) []string {
+	out := make([]string, 0, len(paths)+1)
+	for _, p := range append(append([]string{}, paths...), homeLibrary) {
+		if strings.HasPrefix(p, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			p = filepath.Join(home, p[2:])
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// builtinFunctions lists the names of jq's builtin functions.
+var builtinFunctions = sync.OnceValue(func() []string {
+	query, err := gojq.Parse("builtins")
+	if err != nil {
+		return nil
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil
+	}
+	v, _ := code.Run(nil).Next()
+	list, _ := v.([]any)
+
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		s, _ := item.(string)
+		if name, _, ok := strings.Cut(s, "/"); ok && !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	return names
+})
+
+// Functions lists the functions a filter can call: jq builtins, definitions
+// from ~/.jq, and functions from modules the filter imports (as alias::name)
+// or includes.
+func (s *Service) Functions(filter string) []string {
+	names := append([]string{}, builtinFunctions()...)
+	names = append(names, s.libraryFunctions()...)
+
+	for _, m := range importRe.FindAllStringSubmatch(filter, -1) {
+		directive, module, alias := m[1], m[2], m[3]
+		funcs := s.moduleFunctions(module)
+		if directive == "import" {
+			if alias == "" {
+				continue
+			}
+			for _, fn := range funcs {
+				names = append(names, alias+"::"+fn)
+			}
+			continue
+		}
+		names = append(names, funcs...)
+	}
+
+	sort.Strings(names)
+	return dedupeSorted(names)
+}
+
+// libraryFunctions returns the definitions of a ~/.jq file, if present.
+func (s *Service) libraryFunctions() []string {
+	for _, p := range s.modulePaths {
+		if filepath.Base(p) != ".jq" {
+			continue
+		}
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return s.functionsInFile(p)
+		}
+	}
+	return nil
+}
+
+// moduleFunctions returns the definitions of the named module, looked up the
+// same way gojq resolves imports.
+func (s *Service) moduleFunctions(name string) []string {
+	for _, base := range s.modulePaths {
+		candidates := []string{
+			filepath.Join(base, name+".jq"),
+			filepath.Join(base, name, filepath.Base(name)+".jq"),
+		}
+		for _, path := range candidates {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return s.functionsInFile(path)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) functionsInFile(path string) []string {
+	s.mu.RLock()
+	funcs, ok := s.funcCache[path]
+	s.mu.RUnlock()
+	if ok {
+		return funcs
+	}
+
+	src, err := os.ReadFile(path)
+	if err == nil {
+		if query, err := gojq.Parse(string(src)); err == nil {
+			for _, fd := range query.FuncDefs {
+				funcs = append(funcs, fd.Name)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.funcCache[path] = funcs
+	s.mu.Unlock()
+	return funcs
+}
+
+func dedupeSorted(in []string) []string {
+	out := in[:0]
+	for i, v := range in {
+		if i == 0 || v != in[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package jq
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestModules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".jq"), []byte(`def shout: ascii_upcase + "!";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	libDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(libDir, "util.jq"), []byte(`def double: . * 2; def triple: . * 3;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewServiceWithConfig([]byte(`{"name":"gijq","n":21}`), Config{ModulePaths: []string{libDir}})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`.name | shout`, `"GIJQ!"`},
+		{`import "util" as u; .n | u::double`, `42`},
+		{`include "util"; .n | triple`, `63`},
+	}
+	for _, tt := range tests {
+		result := svc.Execute(tt.filter)
+		if result.Error != nil {
+			t.Fatalf("Execute(%q) failed: %v", tt.filter, result.Error)
+		}
+		if result.Raw != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.filter, result.Raw, tt.want)
+		}
+	}
+
+	if result := svc.Execute(`import "missing" as m; .`); result.Error == nil {
+		t.Error("expected error for missing module")
+	}
+
+	funcs := svc.Functions(`import "util" as u; include "util"; .n | `)
+	for _, want := range []string{"shout", "u::double", "u::triple", "double", "map", "select"} {
+		if !slices.Contains(funcs, want) {
+			t.Errorf("Functions() missing %q", want)
+		}
+	}
+	if slices.Contains(svc.Functions(`.`), "u::double") {
+		t.Error("Functions() should only list module functions that are imported")
+	}
+}
+
+func TestModuleReadsInput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	libDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(libDir, "m.jq"), []byte(`def nxt: input;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := NewServiceWithConfig([]byte(`1 2 3 4`), Config{ModulePaths: []string{libDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run reuses the cached code and must start from the first input
+	for range 2 {
+		result := svc.Execute(`include "m"; [., nxt] | add`)
+		if result.Error != nil || result.Raw != "3\n7" {
+			t.Fatalf("Execute = %q, %v", result.Raw, result.Error)
+		}
+	}
+}
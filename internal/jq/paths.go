@@ -0,0 +1,47 @@
+package jq
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormatPath renders a path of object keys and array indices as a jq
+// expression, such as .items[42].metrics.count. The empty path is ".".
+func FormatPath(path []any) string {
+	if len(path) == 0 {
+		return "."
+	}
+	var b strings.Builder
+	for _, p := range path {
+		switch k := p.(type) {
+		case int:
+			if b.Len() == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString("[" + strconv.Itoa(k) + "]")
+		case string:
+			if isIdentifier(k) {
+				b.WriteString("." + k)
+			} else {
+				b.WriteString("." + FormatValue(k, OutputOptions{}))
+			}
+		}
+	}
+	return b.String()
+}
+
+// isIdentifier reports whether key can follow a dot unquoted.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package jq
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SchemaOptions bounds the work done inferring a schema from large data.
+type SchemaOptions struct {
+	MaxItems    int // Array elements sampled per array
+	MaxValues   int // Values visited in total
+	MaxExamples int // Distinct examples kept per path
+}
+
+// DefaultSchemaOptions suit interactive use on large documents.
+var DefaultSchemaOptions = SchemaOptions{MaxItems: 1000, MaxValues: 200000, MaxExamples: 3}
+
+// Schema summarises the values observed at one path. Types are JSON Schema
+// names, with whole numbers counted as integer.
+type Schema struct {
+	Count      int            // Values observed
+	Types      map[string]int // Values observed per type
+	Examples   []any          // Distinct scalar examples, in order seen
+	Properties map[string]*Schema
+	Items      *Schema // Elements of the arrays observed
+	Sampled    bool    // Some values below this path were skipped
+}
+
+// SchemaTypes lists JSON Schema type names in display order.
+var SchemaTypes = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// InferSchema describes values, treating each one as an observation of the
+// same path.
+func InferSchema(values []any, opts SchemaOptions) *Schema {
+	inf := schemaInferrer{opts: opts, budget: opts.MaxValues}
+	root := newSchema()
+	for _, v := range values {
+		if !inf.observe(root, v) {
+			root.Sampled = true
+			break
+		}
+	}
+	return root
+}
+
+func newSchema() *Schema {
+	return &Schema{Types: map[string]int{}}
+}
+
+type schemaInferrer struct {
+	opts   SchemaOptions
+	budget int
+}
+
+// observe records v in s. It returns false once the value budget is spent.
+func (inf *schemaInferrer) observe(s *Schema, v any) bool {
+	if inf.opts.MaxValues > 0 {
+		if inf.budget <= 0 {
+			return false
+		}
+		inf.budget--
+	}
+
+	t := schemaType(v)
+	s.Count++
+	s.Types[t]++
+
+	switch val := v.(type) {
+	case map[string]any:
+		if s.Properties == nil {
+			s.Properties = map[string]*Schema{}
+		}
+		for _, k := range sortedKeys(val) {
+			prop := s.Properties[k]
+			if prop == nil {
+				prop = newSchema()
+				s.Properties[k] = prop
+			}
+			if !inf.observe(prop, val[k]) {
+				s.Sampled = true
+				return false
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			s.Items = newSchema()
+		}
+		for _, i := range sampleIndexes(len(val), inf.opts.MaxItems) {
+			if !inf.observe(s.Items, val[i]) {
+				s.Items.Sampled = true
+				return false
+			}
+		}
+		if inf.opts.MaxItems > 0 && len(val) > inf.opts.MaxItems {
+			s.Items.Sampled = true
+		}
+	default:
+		s.addExample(v, inf.opts.MaxExamples)
+	}
+	return true
+}
+
+func (s *Schema) addExample(v any, limit int) {
+	if len(s.Examples) >= limit {
+		return
+	}
+	text := FormatValue(v, OutputOptions{Compact: true})
+	for _, e := range s.Examples {
+		if FormatValue(e, OutputOptions{Compact: true}) == text {
+			return
+		}
+	}
+	s.Examples = append(s.Examples, v)
+}
+
+// sampleIndexes picks up to limit indexes spread evenly over n elements, so
+// a long array is represented by its start, middle and end.
+func sampleIndexes(n, limit int) []int {
+	if limit <= 0 || n <= limit {
+		limit = n
+	}
+	idx := make([]int, limit)
+	for i := range idx {
+		idx[i] = i
+		if n > limit && limit > 1 {
+			idx[i] = i * (n - 1) / (limit - 1)
+		}
+	}
+	return idx
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaType names the JSON Schema type of v.
+func schemaType(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if !strings.ContainsAny(val.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "integer"
+}
+
+// TypeNames lists the types observed, in SchemaTypes order.
+func (s *Schema) TypeNames() []string {
+	var names []string
+	for _, t := range SchemaTypes {
+		if s.Types[t] > 0 {
+			names = append(names, t)
+		}
+	}
+	return names
+}
+
+// PropertyNames lists the object keys observed, sorted.
+func (s *Schema) PropertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONSchema renders s as a JSON Schema (draft 2020-12) document. Keys seen
+// in every object are required; everything else is optional.
+func (s *Schema) JSONSchema() map[string]any {
+	doc := s.jsonSchema()
+	doc["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return doc
+}
+
+func (s *Schema) jsonSchema() map[string]any {
+	doc := map[string]any{}
+	types := s.TypeNames()
+	// integer is a subset of number
+	if s.Types["integer"] > 0 && s.Types["number"] > 0 {
+		types = removeString(types, "integer")
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		doc["type"] = types[0]
+	default:
+		list := make([]any, len(types))
+		for i, t := range types {
+			list[i] = t
+		}
+		doc["type"] = list
+	}
+
+	if s.Properties != nil {
+		props := map[string]any{}
+		var required []any
+		for _, k := range s.PropertyNames() {
+			prop := s.Properties[k]
+			props[k] = prop.jsonSchema()
+			if prop.Count == s.Types["object"] {
+				required = append(required, k)
+			}
+		}
+		doc["properties"] = props
+		if len(required) > 0 {
+			doc["required"] = required
+		}
+	}
+	if s.Items != nil && s.Items.Count > 0 {
+		doc["items"] = s.Items.jsonSchema()
+	}
+	if len(s.Examples) > 0 {
+		doc["examples"] = append([]any(nil), s.Examples...)
+	}
+	return doc
+}
+
+func removeString(list []string, s string) []string {
+	out := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
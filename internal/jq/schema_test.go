@@ -0,0 +1,74 @@
+package jq
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	values, err := DecodeValues([]byte(`[
+		{"id": 1, "email": "a@x.io", "score": 1.5},
+		{"id": 2, "email": null, "tags": ["x"]},
+		{"id": 3, "email": "c@x.io", "score": 2}
+	]`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	s := InferSchema(values, SchemaOptions{MaxItems: 100, MaxExamples: 2})
+	items := s.Items
+	if items == nil || items.Count != 3 || items.Types["object"] != 3 {
+		t.Fatalf("items = %+v, want 3 objects", items)
+	}
+
+	email := items.Properties["email"]
+	if got := email.TypeNames(); len(got) != 2 || got[0] != "string" || got[1] != "null" {
+		t.Errorf("email types = %v, want [string null]", got)
+	}
+	if len(email.Examples) != 2 {
+		t.Errorf("email examples = %v, want 2 (the limit)", email.Examples)
+	}
+	if tags := items.Properties["tags"]; tags.Count != 1 {
+		t.Errorf("tags present in %d objects, want 1", tags.Count)
+	}
+
+	doc := s.JSONSchema()
+	itemDoc := doc["items"].(map[string]any)
+	required := itemDoc["required"].([]any)
+	if len(required) != 2 || required[0] != "email" || required[1] != "id" {
+		t.Errorf("required = %v, want [email id]", required)
+	}
+	props := itemDoc["properties"].(map[string]any)
+	if got := props["score"].(map[string]any)["type"]; got != "number" {
+		t.Errorf("score type = %v, want number (integer folds into number)", got)
+	}
+	if got := props["id"].(map[string]any)["type"]; got != "integer" {
+		t.Errorf("id type = %v, want integer", got)
+	}
+	// The document must be printable by the formatter
+	if FormatValue(doc, OutputOptions{}) == "" {
+		t.Fatal("schema did not format")
+	}
+}
+
+func TestInferSchemaSampling(t *testing.T) {
+	arr := make([]any, 5000)
+	for i := range arr {
+		arr[i] = json.Number("1")
+	}
+	arr[len(arr)-1] = "last"
+
+	s := InferSchema([]any{arr}, SchemaOptions{MaxItems: 10})
+	if s.Items.Count != 10 || !s.Items.Sampled {
+		t.Fatalf("items = %d sampled=%v, want 10 sampled", s.Items.Count, s.Items.Sampled)
+	}
+	// Samples are spread across the array, so the last element is seen
+	if s.Items.Types["string"] != 1 {
+		t.Fatalf("types = %v, want the final string sampled", s.Items.Types)
+	}
+
+	s = InferSchema([]any{arr}, SchemaOptions{MaxValues: 100})
+	if s.Items.Count != 99 || !s.Items.Sampled {
+		t.Fatalf("budgeted items = %d sampled=%v, want 99 sampled", s.Items.Count, s.Items.Sampled)
+	}
+}
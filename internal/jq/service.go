@@ -1,14 +1,17 @@
 package jq
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/itchyny/gojq"
 )
@@ -17,34 +20,261 @@ import (
 type Result struct {
 	Colored string // Syntax highlighted for display
 	Raw     string // Plain text for clipboard
+	Values  []any  // The filter's outputs, for exports
 	Error   error
 }
 
+// Config holds options for building a Service
+type Config struct {
+	// Stream treats the input as JSON Lines: every non-blank line is decoded
+	// as its own value. Without it, inputs holding several concatenated
+	// values are still detected and run as a stream.
+	Stream bool
+
+	// InputFormat selects the decoder; empty means JSON. YAML inputs hold
+	// one value per document.
+	InputFormat InputFormat
+
+	// Slurp reads every input value into one array and runs the filter once
+	// against it, like jq -s.
+	Slurp bool
+
+	// NullInput runs the filter once against null, leaving the inputs to be
+	// read with input and inputs, like jq -n.
+	NullInput bool
+
+	// Output sets the initial result formatting.
+	Output OutputOptions
+
+	// Variables are bound as $name in every filter, like jq --arg and
+	// --argjson. Names are given without the leading $.
+	Variables map[string]any
+
+	// ModulePaths are searched by import and include, like jq -L. ~/.jq is
+	// always searched last.
+	ModulePaths []string
+
+	// Limits bound the output Stream collects for display.
+	Limits Limits
+}
+
+// Limits bound how much output a query collects for display, so a careless
+// filter cannot exhaust memory. Zero means no limit.
+type Limits struct {
+	Results int // Outputs kept
+	Bytes   int // Formatted size of the outputs kept, estimated
+}
+
+// DefaultLimits leave room for any result worth scrolling through.
+var DefaultLimits = Limits{Results: 1000000, Bytes: 256 << 20}
+
+// Variable is a named value available to filters as $Name
+type Variable struct {
+	Name  string // Includes the leading $
+	Value any
+}
+
 // Service wraps gojq for executing jq filters
 type Service struct {
-	data any // Parsed JSON kept in memory
+	data      any   // Parsed JSON kept in memory
+	inputs    []any // Every top-level input value, in order
+	format    InputFormat
+	stream    bool
+	slurp     bool
+	nullInput bool
+	variables []Variable
+	limits    Limits
+
+	modulePaths  []string
+	moduleLoader gojq.ModuleLoader
 
 	mu        sync.RWMutex
-	codeCache map[string]*gojq.Code
+	output    OutputOptions
+	codeCache map[string][]*boundCode
 	keysCache map[string][]string
+	funcCache map[string][]string
+
+	// Input loading; data and inputs are set before loaded is closed
+	loaded   chan struct{}
+	loadErr  error
+	loadRead atomic.Int64
+	loadSize atomic.Int64
 }
 
 // NewService creates a jq service from JSON bytes
 func NewService(jsonData []byte) (*Service, error) {
-	if len(jsonData) == 0 {
+	return NewServiceWithConfig(jsonData, Config{})
+}
+
+// NewServiceWithConfig creates a jq service from input bytes using cfg
+func NewServiceWithConfig(jsonData []byte, cfg Config) (*Service, error) {
+	if len(jsonData) == 0 && !cfg.NullInput {
 		return nil, fmt.Errorf("empty input")
 	}
 
-	var data any
-	if err := json.Unmarshal(jsonData, &data); err != nil {
-		return nil, fmt.Errorf("invalid JSON: %w", err)
+	s := NewLoadingService(cfg)
+	if err := s.setInputs(decodeInput(jsonData, cfg)); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
+
+// NewServiceFromReader creates a jq service from input read from r. JSON
+// is decoded as it is read rather than after reading it all.
+func NewServiceFromReader(r io.Reader, cfg Config) (*Service, error) {
+	s := NewLoadingService(cfg)
+	if err := s.Load(r, 0); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
 
+// NewLoadingService creates a jq service whose input is supplied later by
+// Load. Filters wait for the input; until then Data and Inputs are empty.
+func NewLoadingService(cfg Config) *Service {
+	modulePaths := resolveModulePaths(cfg.ModulePaths)
 	return &Service{
-		data:      data,
-		codeCache: map[string]*gojq.Code{},
-		keysCache: map[string][]string{},
-	}, nil
+		format:       cfg.InputFormat,
+		stream:       cfg.Stream,
+		slurp:        cfg.Slurp,
+		nullInput:    cfg.NullInput,
+		limits:       cfg.Limits,
+		output:       cfg.Output,
+		variables:    buildVariables(cfg.Variables),
+		modulePaths:  modulePaths,
+		moduleLoader: gojq.NewModuleLoader(modulePaths),
+		codeCache:    map[string][]*boundCode{},
+		keysCache:    map[string][]string{},
+		funcCache:    map[string][]string{},
+		loaded:       make(chan struct{}),
+	}
+}
+
+// Load reads the input from r, size bytes long if known, and makes it
+// available to filters. It must be called once.
+func (s *Service) Load(r io.Reader, size int64) error {
+	s.loadSize.Store(size)
+	r = &countingReader{r: r, n: &s.loadRead}
+
+	var inputs []any
+	var err error
+	switch {
+	case s.format != "" && s.format != FormatJSON:
+		var data []byte
+		if data, err = io.ReadAll(r); err == nil {
+			inputs, err = decodeInput(data, Config{InputFormat: s.format})
+		}
+	case s.stream:
+		inputs, err = readLines(r)
+	default:
+		inputs, err = readValues(r)
+	}
+	return s.setInputs(inputs, err)
+}
+
+// setInputs records the decoded input, or the error decoding it, and
+// releases waiting filters.
+func (s *Service) setInputs(inputs []any, err error) error {
+	defer close(s.loaded)
+	if err == nil && len(inputs) == 0 && !s.nullInput {
+		err = fmt.Errorf("empty input")
+	}
+	if err != nil {
+		s.loadErr = err
+		return err
+	}
+	if s.slurp {
+		slurped := inputs
+		if slurped == nil {
+			slurped = []any{}
+		}
+		inputs = []any{slurped}
+	}
+
+	switch len(inputs) {
+	case 0:
+	case 1:
+		s.data = inputs[0]
+	default:
+		s.data = inputs
+	}
+	s.inputs = inputs
+	return nil
+}
+
+// Loaded reports whether the input has been read, successfully or not.
+func (s *Service) Loaded() bool {
+	select {
+	case <-s.loaded:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitLoaded blocks until the input has been read and returns the error
+// that stopped it, if any.
+func (s *Service) WaitLoaded() error {
+	<-s.loaded
+	return s.loadErr
+}
+
+// LoadProgress returns how many input bytes have been read and how many
+// there are in all, or 0 when that is not known.
+func (s *Service) LoadProgress() (read, total int64) {
+	return s.loadRead.Load(), s.loadSize.Load()
+}
+
+// ErrNotLoaded is returned by lookups that do not wait for the input.
+var ErrNotLoaded = errors.New("input is still loading")
+
+// waitLoaded is WaitLoaded giving up when ctx is done.
+func (s *Service) waitLoaded(ctx context.Context) error {
+	select {
+	case <-s.loaded:
+		return s.loadErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countingReader adds the bytes read through it to n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// buildVariables orders the configured variables by name and adds $ARGS
+// the way jq does.
+func buildVariables(named map[string]any) []Variable {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make([]Variable, 0, len(names)+1)
+	argsNamed := make(map[string]any, len(names))
+	for _, name := range names {
+		vars = append(vars, Variable{Name: "$" + name, Value: named[name]})
+		argsNamed[name] = named[name]
+	}
+	vars = append(vars, Variable{
+		Name:  "$ARGS",
+		Value: map[string]any{"named": argsNamed, "positional": []any{}},
+	})
+	return vars
+}
+
+// DecodeValues parses a sequence of whitespace-separated JSON values.
+func DecodeValues(data []byte) ([]any, error) {
+	return decodeValues(data)
 }
 
 // Execute runs a jq filter and returns the result
@@ -54,87 +284,303 @@ func (s *Service) Execute(filter string) Result {
 
 // ExecuteWithContext runs a jq filter and supports cancellation.
 func (s *Service) ExecuteWithContext(ctx context.Context, filter string) Result {
-	code, err := s.compiledQuery(filter)
+	var results []any
+	err := s.run(ctx, filter, func(v any) bool {
+		results = append(results, v)
+		return true
+	})
 	if err != nil {
 		return Result{Error: err}
 	}
 
-	var results []any
-	iter := code.RunWithContext(ctx, s.data)
-	for {
-		v, ok := iter.Next()
-		if !ok {
-			break
-		}
-		if ctx.Err() != nil {
-			return Result{Error: ctx.Err()}
-		}
-		if err, isErr := v.(error); isErr {
-			return Result{Error: err}
+	return formatOutput(results, s.OutputOptions())
+}
+
+// WriteResults runs filter and writes its output to w as it is produced,
+// followed by a newline, without the limits Stream applies. Layouts of all
+// results together (tables, gron, schemas) are written once evaluation
+// finishes.
+func (s *Service) WriteResults(ctx context.Context, filter string, w io.Writer) error {
+	opts := s.OutputOptions()
+	if wholeOutput(opts) {
+		return writeWhole(w, s.ExecuteWithContext(ctx, filter))
+	}
+
+	out := newResultWriter(w, opts)
+	var writeErr error
+	err := s.run(ctx, filter, func(v any) bool {
+		writeErr = out.write(v)
+		return writeErr == nil
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if closeErr := out.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeWhole writes the text of a finished result followed by a newline.
+func writeWhole(w io.Writer, result Result) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.Raw == "" {
+		return nil
+	}
+	_, err := fmt.Fprintln(w, result.Raw)
+	return err
+}
+
+// formatOutput lays out results as text using opts.
+func formatOutput(results []any, opts OutputOptions) Result {
+	if opts.Table != "" {
+		raw, err := ExportTable(results, opts.Table)
+		if err != nil {
+			return Result{Values: results, Error: err}
 		}
-		results = append(results, v)
+		return Result{Raw: raw, Colored: raw, Values: results}
+	}
+	if opts.Gron {
+		raw := Gron(results)
+		return Result{Raw: raw, Colored: raw, Values: results}
+	}
+	if opts.Schema {
+		schema := InferSchema(results, DefaultSchemaOptions).JSONSchema()
+		raw := FormatValue(schema, OutputOptions{Compact: opts.Compact, Tab: opts.Tab, YAML: opts.YAML})
+		return Result{Raw: raw, Colored: Colorize(raw), Values: results}
 	}
 
-	raw := formatResults(results)
+	raw := formatResults(results, opts)
 	colored := Colorize(raw)
 
-	return Result{Raw: raw, Colored: colored}
+	return Result{Raw: raw, Colored: colored, Values: results}
 }
 
-func formatResults(results []any) string {
-	var buf bytes.Buffer
-	for i, r := range results {
-		if i > 0 {
-			buf.WriteByte('\n')
+// run evaluates filter the way jq does: once per input value, or once
+// against null in null-input mode. Inputs not yet consumed by the main loop
+// are available to the filter through input and inputs. Evaluation stops
+// early, without error, when emit returns false.
+func (s *Service) run(ctx context.Context, filter string, emit func(any) bool) error {
+	bound, err := s.acquireCode(filter)
+	if err != nil {
+		return err
+	}
+	defer s.releaseCode(filter, bound)
+	if err := s.waitLoaded(ctx); err != nil {
+		return err
+	}
+	code, cursor := bound.code, bound.cursor
+	cursor.values = s.inputs
+
+	next := cursor.advance
+	if s.nullInput {
+		done := false
+		next = func() (any, bool) {
+			if done {
+				return nil, false
+			}
+			done = true
+			return nil, true
 		}
-		b, err := json.MarshalIndent(r, "", "  ")
-		if err != nil {
-			buf.WriteString(fmt.Sprintf("%v", r))
-		} else {
-			buf.Write(b)
+	}
+
+	for {
+		input, ok := next()
+		if !ok {
+			return nil
+		}
+		iter := code.RunWithContext(ctx, input, s.variableValues()...)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err, isErr := v.(error); isErr {
+				if errors.Is(err, errInputsExhausted) {
+					return errors.New("no more inputs")
+				}
+				return err
+			}
+			if !emit(v) {
+				return nil
+			}
 		}
 	}
-	return buf.String()
 }
 
-// Data returns the parsed JSON data (for autocomplete)
+// errInputsExhausted is raised by input once every value has been read. gojq
+// defines inputs to stop quietly on an error reading "break".
+var errInputsExhausted = errors.New("break")
+
+// inputCursor hands out input values in order. It backs both the main
+// evaluation loop and the input/inputs builtins so each value is read once.
+type inputCursor struct {
+	values []any
+	pos    int
+}
+
+// advance returns the next input for the main evaluation loop.
+func (c *inputCursor) advance() (any, bool) {
+	if c.pos >= len(c.values) {
+		return nil, false
+	}
+	v := c.values[c.pos]
+	c.pos++
+	return v, true
+}
+
+// Next serves the input builtin, raising errInputsExhausted past the end so
+// run can tell it from errors the filter raises itself.
+func (c *inputCursor) Next() (any, bool) {
+	if v, ok := c.advance(); ok {
+		return v, true
+	}
+	return errInputsExhausted, true
+}
+
+// boundCode is compiled code together with the cursor its input and inputs
+// builtins read from. gojq fixes the input iterator at compile time, so each
+// run takes a boundCode out of the cache and returns it when done; concurrent
+// runs of one filter never share a cursor.
+type boundCode struct {
+	code   *gojq.Code
+	cursor *inputCursor
+}
+
+// OutputOptions returns the current result formatting.
+func (s *Service) OutputOptions() OutputOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.output
+}
+
+// SetOutputOptions changes how subsequent results are formatted.
+func (s *Service) SetOutputOptions(opts OutputOptions) {
+	s.mu.Lock()
+	s.output = opts
+	s.mu.Unlock()
+}
+
+// Data returns the parsed JSON data (for autocomplete). For a stream of
+// inputs this is an array holding every value.
 func (s *Service) Data() any {
+	if !s.Loaded() {
+		return nil
+	}
 	return s.data
 }
 
-// KeysAt returns available keys at the given jq path
+// Inputs returns every top-level input value in order.
+func (s *Service) Inputs() []any {
+	if !s.Loaded() {
+		return nil
+	}
+	return s.inputs
+}
+
+// NullInput reports whether filters run against null rather than the inputs.
+func (s *Service) NullInput() bool {
+	return s.nullInput
+}
+
+// IsStream reports whether the input holds more than one value.
+func (s *Service) IsStream() bool {
+	return len(s.Inputs()) > 1
+}
+
+// Completing keys on a stream samples at most maxKeyInputs inputs and stops
+// evaluating after keysTimeout with the keys found so far.
+const (
+	maxKeyInputs = 10000
+	keysTimeout  = time.Second
+)
+
+// KeysAt returns available keys at the given jq path, or ErrNotLoaded
+// while the input is loading. On a stream, inputs the path fails on are
+// skipped; the error is returned only when it fails on all of them.
 func (s *Service) KeysAt(path string) ([]string, error) {
 	if path == "" {
 		path = "."
 	}
 
+	if !s.Loaded() {
+		return nil, ErrNotLoaded
+	}
+	if s.loadErr != nil {
+		return nil, s.loadErr
+	}
 	if keys, ok := s.cachedKeys(path); ok {
 		return keys, nil
 	}
 
-	if keys, ok := keysAtSimplePath(s.data, path); ok {
-		s.storeKeys(path, keys)
-		return cloneStrings(keys), nil
+	// Keys are the union over the inputs so streams complete fully.
+	starts := s.inputs
+	if s.nullInput {
+		starts = []any{nil}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keysTimeout)
+	defer cancel()
+	var keys []string
+	var firstErr error
+	found := false
+	seen := map[string]struct{}{}
+	for _, input := range starts[:min(len(starts), maxKeyInputs)] {
+		inputKeys, err := s.keysAtInput(ctx, input, path)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			// One odd line of a log does not spoil completion for the rest
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		for _, k := range inputKeys {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				keys = append(keys, k)
+			}
+		}
+	}
+	if !found && firstErr != nil {
+		return nil, firstErr
+	}
+	if len(s.inputs) > 1 && len(keys) > 0 && !strings.HasPrefix(keys[0], "[") {
+		sort.Strings(keys)
+	}
+
+	s.storeKeys(path, keys)
+	return cloneStrings(keys), nil
+}
+
+func (s *Service) keysAtInput(ctx context.Context, input any, path string) ([]string, error) {
+	if keys, ok := keysAtSimplePath(input, path); ok {
+		return keys, nil
 	}
 
-	code, err := s.compiledQuery(path)
+	bound, err := s.acquireCode(path)
 	if err != nil {
 		return nil, err
 	}
+	defer s.releaseCode(path, bound)
+	bound.cursor.values = s.inputs
 
-	iter := code.Run(s.data)
+	iter := bound.code.RunWithContext(ctx, input, s.variableValues()...)
 	v, ok := iter.Next()
 	if !ok {
-		s.storeKeys(path, nil)
 		return nil, nil
 	}
 	if err, isErr := v.(error); isErr {
 		return nil, err
 	}
-
-	keys := extractKeys(v)
-	s.storeKeys(path, keys)
-	return cloneStrings(keys), nil
+	return extractKeys(v), nil
 }
 
 func extractKeys(v any) []string {
@@ -179,32 +625,66 @@ func (s *Service) storeKeys(path string, keys []string) {
 	s.mu.Unlock()
 }
 
-func (s *Service) compiledQuery(filter string) (*gojq.Code, error) {
-	s.mu.RLock()
-	if code, ok := s.codeCache[filter]; ok {
-		s.mu.RUnlock()
-		return code, nil
+// acquireCode returns compiled code for filter with a cursor of its own,
+// taken from the cache when a previous run left one there.
+func (s *Service) acquireCode(filter string) (*boundCode, error) {
+	s.mu.Lock()
+	if free := s.codeCache[filter]; len(free) > 0 {
+		bound := free[len(free)-1]
+		s.codeCache[filter] = free[:len(free)-1]
+		s.mu.Unlock()
+		return bound, nil
 	}
-	s.mu.RUnlock()
+	s.mu.Unlock()
 
 	query, err := gojq.Parse(filter)
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
 
-	code, err := gojq.Compile(query)
+	cursor := &inputCursor{}
+	code, err := gojq.Compile(query, s.compilerOptions(gojq.WithInputIter(cursor))...)
 	if err != nil {
 		return nil, fmt.Errorf("compile error: %w", err)
 	}
+	return &boundCode{code: code, cursor: cursor}, nil
+}
 
+// releaseCode puts code taken by acquireCode back in the cache.
+func (s *Service) releaseCode(filter string, bound *boundCode) {
+	bound.cursor.values, bound.cursor.pos = nil, 0
 	s.mu.Lock()
-	if existing, ok := s.codeCache[filter]; ok {
-		s.mu.Unlock()
-		return existing, nil
-	}
-	s.codeCache[filter] = code
+	s.codeCache[filter] = append(s.codeCache[filter], bound)
 	s.mu.Unlock()
-	return code, nil
+}
+
+func (s *Service) compilerOptions(extra ...gojq.CompilerOption) []gojq.CompilerOption {
+	names := make([]string, len(s.variables))
+	for i, v := range s.variables {
+		names[i] = v.Name
+	}
+	opts := []gojq.CompilerOption{
+		gojq.WithVariables(names),
+		gojq.WithEnvironLoader(os.Environ),
+		gojq.WithModuleLoader(s.moduleLoader),
+	}
+	return append(opts, extra...)
+}
+
+func (s *Service) variableValues() []any {
+	values := make([]any, len(s.variables))
+	for i, v := range s.variables {
+		values[i] = v.Value
+	}
+	return values
+}
+
+// Variables returns the variables bound in every filter, ordered by name
+// with $ARGS last.
+func (s *Service) Variables() []Variable {
+	out := make([]Variable, len(s.variables))
+	copy(out, s.variables)
+	return out
 }
 
 func cloneStrings(in []string) []string {
@@ -1,8 +1,10 @@
 package jq
 
 import (
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewService(t *testing.T) {
@@ -117,6 +119,26 @@ func TestKeysAt(t *testing.T) {
 	}
 }
 
+func TestKeysAtSkipsFailingInputs(t *testing.T) {
+	svc, err := NewService([]byte("{\"a\":{\"x\":1}}\n\"text\"\n{\"a\":{\"y\":2}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := svc.KeysAt(`.a | .`)
+	if err != nil || !equalSlices(keys, []string{"x", "y"}) {
+		t.Fatalf("KeysAt = %v, %v; want [x y]", keys, err)
+	}
+	if _, err := svc.KeysAt(`.a | error("boom")`); err == nil {
+		t.Fatal("a path failing on every input should report the error")
+	}
+
+	// Paths that never finish are abandoned
+	start := time.Now()
+	if _, err := svc.KeysAt(`last(repeat(.))`); err != nil || time.Since(start) > 3*keysTimeout {
+		t.Fatalf("endless path: %v after %v", err, time.Since(start))
+	}
+}
+
 func equalSlices(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -128,3 +150,203 @@ func equalSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestStreamInput(t *testing.T) {
+	ndjson := "{\"level\":\"info\",\"msg\":\"start\"}\n{\"level\":\"error\",\"msg\":\"boom\",\"code\":7}\n\n{\"level\":\"info\",\"msg\":\"done\"}\n"
+
+	for _, cfg := range []Config{{}, {Stream: true}} {
+		svc, err := NewServiceWithConfig([]byte(ndjson), cfg)
+		if err != nil {
+			t.Fatalf("NewServiceWithConfig(%+v) failed: %v", cfg, err)
+		}
+		if !svc.IsStream() {
+			t.Fatalf("IsStream() = false, want true (%+v)", cfg)
+		}
+
+		result := svc.Execute(`select(.level == "error") | .msg`)
+		if result.Error != nil {
+			t.Fatalf("Execute failed: %v", result.Error)
+		}
+		if result.Raw != `"boom"` {
+			t.Errorf("Raw = %q, want %q", result.Raw, `"boom"`)
+		}
+
+		result = svc.Execute(".msg")
+		if result.Raw != "\"start\"\n\"boom\"\n\"done\"" {
+			t.Errorf("Raw = %q, want one output per input", result.Raw)
+		}
+
+		keys, err := svc.KeysAt(".")
+		if err != nil {
+			t.Fatalf("KeysAt failed: %v", err)
+		}
+		if want := []string{"code", "level", "msg"}; !equalSlices(keys, want) {
+			t.Errorf("KeysAt(.) = %v, want %v", keys, want)
+		}
+	}
+}
+
+func TestStreamInputErrors(t *testing.T) {
+	_, err := NewServiceWithConfig([]byte("{\"a\":1}\n{bad\n"), Config{Stream: true})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("error = %v, want line number", err)
+	}
+
+	_, err = NewService([]byte(`{"a":1} {bad`))
+	if err == nil || !strings.Contains(err.Error(), "value 2") {
+		t.Fatalf("error = %v, want value index", err)
+	}
+}
+
+func TestSlurpAndNullInput(t *testing.T) {
+	input := []byte(`{"n":1} {"n":2} {"n":3}`)
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		filter string
+		want   string
+	}{
+		{"slurp", Config{Slurp: true}, "map(.n) | add", "6"},
+		{"slurp length", Config{Slurp: true}, "length", "3"},
+		{"null input", Config{NullInput: true}, ".", "null"},
+		{"null input reduce", Config{NullInput: true}, "reduce inputs as $x (0; . + $x.n)", "6"},
+		{"null input first", Config{NullInput: true}, "input.n", "1"},
+		{"input consumes stream", Config{}, `[.n, (try input.n catch "none")]`, "[\n  1,\n  2\n]\n[\n  3,\n  \"none\"\n]"},
+		{"null input slurp", Config{NullInput: true, Slurp: true}, "input | length", "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := NewServiceWithConfig(input, tt.cfg)
+			if err != nil {
+				t.Fatalf("NewServiceWithConfig failed: %v", err)
+			}
+			// Run twice to make sure input state does not leak between runs.
+			for i := 0; i < 2; i++ {
+				result := svc.Execute(tt.filter)
+				if result.Error != nil {
+					t.Fatalf("Execute(%q) failed: %v", tt.filter, result.Error)
+				}
+				if result.Raw != tt.want {
+					t.Errorf("Execute(%q) = %q, want %q", tt.filter, result.Raw, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInputExhausted(t *testing.T) {
+	svc, _ := NewServiceWithConfig([]byte(`1`), Config{NullInput: true})
+	result := svc.Execute("input, input")
+	if result.Error == nil || result.Error.Error() != "no more inputs" {
+		t.Fatalf("Error = %v, want no more inputs", result.Error)
+	}
+
+	// The filter's own errors are not mistaken for running out of input
+	if result = svc.Execute(`[inputs] | error("break")`); result.Error == nil || result.Error.Error() != "error: break" {
+		t.Fatalf("Error = %v, want the filter's error", result.Error)
+	}
+	if result = svc.Execute(`[inputs], (try input catch .)`); result.Error != nil || result.Raw != "[\n  1\n]\n\"break\"" {
+		t.Fatalf("Execute = %q, %v", result.Raw, result.Error)
+	}
+}
+
+func TestNullInputWithoutData(t *testing.T) {
+	svc, err := NewServiceWithConfig(nil, Config{NullInput: true})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	result := svc.Execute(`{a: 1} | .a`)
+	if result.Error != nil || result.Raw != "1" {
+		t.Fatalf("Execute = (%q, %v), want 1", result.Raw, result.Error)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte(`{"users":[{"name":"alice"},{"name":"bob"}]}`), Config{
+		Variables: map[string]any{"who": "bob", "n": 1},
+	})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`.users[] | select(.name == $who) | .name`, `"bob"`},
+		{`.users[$n].name`, `"bob"`},
+		{`$ARGS.named.who`, `"bob"`},
+		{`$ARGS.positional | length`, `0`},
+	}
+	for _, tt := range tests {
+		result := svc.Execute(tt.filter)
+		if result.Error != nil {
+			t.Fatalf("Execute(%q) failed: %v", tt.filter, result.Error)
+		}
+		if result.Raw != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.filter, result.Raw, tt.want)
+		}
+	}
+
+	if result := svc.Execute(`$missing`); result.Error == nil {
+		t.Error("expected compile error for undefined variable")
+	}
+
+	vars := svc.Variables()
+	if len(vars) != 3 || vars[0].Name != "$n" || vars[1].Name != "$who" || vars[2].Name != "$ARGS" {
+		t.Errorf("Variables() = %v", vars)
+	}
+}
+
+func TestBigNumberPrecision(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/bignum.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	svc, err := NewService(data)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{".ids[0]", "1234567890123456789"},
+		{".ids[1]", "9007199254740993"},
+		{".ids[2]", "-9223372036854775808"},
+		{".huge", "123456789012345678901234567890"},
+		{".order.id", "18446744073709551615"},
+		{".order.total", "100.10"},
+		{".ratio", "0.1"},
+		{".ids | map(select(. == 1234567890123456789)) | length", "1"},
+		{`.ids[0] | tostring`, `"1234567890123456789"`},
+		{".ids[1] + 0 | . > 9007199254740992", "true"},
+	}
+	for _, tt := range tests {
+		result := svc.Execute(tt.filter)
+		if result.Error != nil {
+			t.Fatalf("Execute(%q) failed: %v", tt.filter, result.Error)
+		}
+		if result.Raw != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.filter, result.Raw, tt.want)
+		}
+	}
+
+	svc.SetOutputOptions(OutputOptions{Compact: true})
+	result := svc.Execute(".ids")
+	if want := "[1234567890123456789,9007199254740993,-9223372036854775808]"; result.Raw != want {
+		t.Errorf("compact .ids = %q, want %q", result.Raw, want)
+	}
+
+	// JSON Lines input keeps precision too.
+	svc, err = NewServiceWithConfig([]byte("{\"id\":1234567890123456789}\n{\"id\":1}\n"), Config{Stream: true})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	if result := svc.Execute(".id"); result.Raw != "1234567890123456789\n1" {
+		t.Errorf("stream .id = %q", result.Raw)
+	}
+}
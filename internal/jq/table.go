@@ -0,0 +1,228 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TableFormat names a tabular export of results
+type TableFormat string
+
+const (
+	TableCSV      TableFormat = "csv"
+	TableTSV      TableFormat = "tsv"
+	TableMarkdown TableFormat = "markdown"
+)
+
+// TableFormats lists the export formats in menu order.
+var TableFormats = []TableFormat{TableCSV, TableTSV, TableMarkdown}
+
+// Label is the display name of the format.
+func (f TableFormat) Label() string {
+	switch f {
+	case TableCSV:
+		return "CSV"
+	case TableTSV:
+		return "TSV"
+	case TableMarkdown:
+		return "Markdown"
+	}
+	return string(f)
+}
+
+// ExportTable renders results as a table. A single array result supplies
+// the rows; otherwise each result is a row. Rows of objects get a header
+// from the union of their keys; rows of arrays are written as they are.
+func ExportTable(results []any, format TableFormat) (string, error) {
+	header, cells, err := Table(results)
+	if err != nil {
+		return "", fmt.Errorf("cannot export as %s: %w", format.Label(), err)
+	}
+
+	switch format {
+	case TableCSV:
+		return writeCSVTable(header, cells)
+	case TableTSV:
+		return writeTSVTable(header, cells), nil
+	case TableMarkdown:
+		return writeMarkdownTable(header, cells), nil
+	}
+	return "", fmt.Errorf("unknown table format %q", format)
+}
+
+// Table lays results out as text cells the way ExportTable does, returning
+// the header for rows of objects or nil for rows of arrays.
+func Table(results []any) ([]string, [][]string, error) {
+	rows := results
+	if len(results) == 1 {
+		if arr, ok := results[0].([]any); ok {
+			rows = arr
+		}
+	}
+	return tableCells(rows)
+}
+
+// tableCells converts rows to text cells, returning the header for rows of
+// objects or nil for rows of arrays.
+func tableCells(rows []any) ([]string, [][]string, error) {
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+
+	if _, ok := rows[0].(map[string]any); ok {
+		header := tableColumns(rows)
+		cells := make([][]string, len(rows))
+		for i, row := range rows {
+			obj, ok := row.(map[string]any)
+			if !ok {
+				return nil, nil, fmt.Errorf("row %d is %s, not an object", i+1, typeName(row))
+			}
+			cells[i] = make([]string, len(header))
+			for j, col := range header {
+				cells[i][j] = cellText(obj[col])
+			}
+		}
+		return header, cells, nil
+	}
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		arr, ok := row.([]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("row %d is %s; rows must be objects or arrays", i+1, typeName(row))
+		}
+		cells[i] = make([]string, len(arr))
+		for j, v := range arr {
+			cells[i][j] = cellText(v)
+		}
+	}
+	return nil, cells, nil
+}
+
+// tableColumns returns the union of the rows' keys: each row's new keys are
+// appended in sorted order, so the first row's columns lead.
+func tableColumns(rows []any) []string {
+	var header []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		obj, ok := row.(map[string]any)
+		if !ok {
+			continue
+		}
+		var added []string
+		for k := range obj {
+			if !seen[k] {
+				seen[k] = true
+				added = append(added, k)
+			}
+		}
+		sort.Strings(added)
+		header = append(header, added...)
+	}
+	return header
+}
+
+// cellText renders a cell: strings as they are, null as empty, and nested
+// values as compact JSON.
+func cellText(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	}
+	return FormatValue(v, OutputOptions{Compact: true})
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case string:
+		return "a string"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return "a number"
+}
+
+func writeCSVTable(header []string, cells [][]string) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if header != nil {
+		w.Write(header)
+	}
+	w.WriteAll(cells)
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// tsvEscaper escapes cells the way jq's @tsv does.
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func writeTSVTable(header []string, cells [][]string) string {
+	var lines []string
+	writeRow := func(row []string) {
+		escaped := make([]string, len(row))
+		for i, c := range row {
+			escaped[i] = tsvEscaper.Replace(c)
+		}
+		lines = append(lines, strings.Join(escaped, "\t"))
+	}
+	if header != nil {
+		writeRow(header)
+	}
+	for _, row := range cells {
+		writeRow(row)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+func writeMarkdownTable(header []string, cells [][]string) string {
+	width := len(header)
+	for _, row := range cells {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if width == 0 {
+		return ""
+	}
+	if header == nil {
+		// Markdown tables need a header row; number the columns
+		header = make([]string, width)
+		for i := range header {
+			header[i] = fmt.Sprintf("%d", i)
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteByte('|')
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = markdownEscaper.Replace(row[i])
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteByte('\n')
+	}
+	writeRow(header)
+	b.WriteString(strings.Repeat("| --- ", width) + "|\n")
+	for _, row := range cells {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
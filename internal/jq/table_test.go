@@ -0,0 +1,103 @@
+package jq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportTable(t *testing.T) {
+	rows := []any{
+		map[string]any{"id": 1, "name": "Ada, Countess", "tags": []any{"math"}},
+		map[string]any{"id": 2, "name": "Grace\tHopper", "team": "navy|ops", "active": true},
+		map[string]any{"id": 3, "name": nil},
+	}
+
+	tests := []struct {
+		name    string
+		results []any
+		format  TableFormat
+		want    string
+	}{
+		{
+			name:    "csv from array",
+			results: []any{rows},
+			format:  TableCSV,
+			want: "id,name,tags,active,team\n" +
+				"1,\"Ada, Countess\",\"[\"\"math\"\"]\",,\n" +
+				"2,Grace\tHopper,,true,navy|ops\n" +
+				"3,,,,",
+		},
+		{
+			name:    "tsv from stream",
+			results: rows,
+			format:  TableTSV,
+			want: "id\tname\ttags\tactive\tteam\n" +
+				"1\tAda, Countess\t[\"math\"]\t\t\n" +
+				"2\tGrace\\tHopper\t\ttrue\tnavy|ops\n" +
+				"3\t\t\t\t",
+		},
+		{
+			name:    "markdown",
+			results: []any{rows[1:]},
+			format:  TableMarkdown,
+			want: "| active | id | name | team |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| true | 2 | Grace\tHopper | navy\\|ops |\n" +
+				"|  | 3 |  |  |",
+		},
+		{
+			name:    "rows of arrays",
+			results: []any{[]any{[]any{"a", 1}, []any{"b", 2}}},
+			format:  TableCSV,
+			want:    "a,1\nb,2",
+		},
+		{
+			name:    "empty",
+			results: []any{[]any{}},
+			format:  TableMarkdown,
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExportTable(tt.results, tt.format)
+			if err != nil {
+				t.Fatalf("ExportTable failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExportTable =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportTableErrors(t *testing.T) {
+	_, err := ExportTable([]any{[]any{map[string]any{"a": 1}, "x"}}, TableCSV)
+	if err == nil || !strings.Contains(err.Error(), "row 2 is a string") {
+		t.Fatalf("err = %v, want row 2 error", err)
+	}
+	_, err = ExportTable([]any{"x", "y"}, TableTSV)
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot export as TSV") {
+		t.Fatalf("err = %v, want TSV error", err)
+	}
+}
+
+func TestTableOutputOption(t *testing.T) {
+	svc, err := NewServiceWithConfig([]byte(`{"items":[{"id":1,"ok":true},{"id":2}]}`), Config{
+		Output: OutputOptions{Table: TableCSV},
+	})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	result := svc.Execute(".items[]")
+	if result.Error != nil {
+		t.Fatalf("Execute failed: %v", result.Error)
+	}
+	if want := "id,ok\n1,true\n2,"; result.Raw != want {
+		t.Errorf("Raw = %q, want %q", result.Raw, want)
+	}
+	if len(result.Values) != 2 {
+		t.Errorf("Values = %v, want both outputs", result.Values)
+	}
+}
@@ -0,0 +1,19 @@
+package jq
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// decodeTOML decodes a TOML document as a single object input.
+func decodeTOML(data []byte) ([]any, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	return []any{normalizeValue(doc)}, nil
+}
@@ -0,0 +1,155 @@
+package jq
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// Violation is a place where a value breaks a JSON Schema.
+type Violation struct {
+	Index   int    // Which of the values passed to ValidateAll failed
+	Path    []any  // jq path of the failing value
+	Keyword string // Schema location of the failed keyword, as a JSON pointer
+	Message string
+}
+
+// Validator checks values against a compiled JSON Schema.
+type Validator struct {
+	schema *jsonschema.Schema
+}
+
+// LoadSchema compiles the JSON Schema in the file at path. References to
+// other files are resolved relative to it.
+func LoadSchema(path string) (*Validator, error) {
+	schema, err := jsonschema.NewCompiler().Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{schema: schema}, nil
+}
+
+// Validate lists every violation in v, in the order the validator reports
+// them. Group failures that only say a nested value failed are left out in
+// favour of the nested failures themselves.
+func (val *Validator) Validate(v any) []Violation {
+	v = schemaInstance(v)
+	err := val.schema.Validate(v)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		if err != nil {
+			return []Violation{{Message: err.Error()}}
+		}
+		return nil
+	}
+
+	var out []Violation
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		if _, ok := unit.Error.Kind.(*kind.Group); ok {
+			continue
+		}
+		out = append(out, Violation{
+			Path:    pointerPath(v, unit.InstanceLocation),
+			Keyword: unit.KeywordLocation,
+			Message: unit.Error.String(),
+		})
+	}
+	return out
+}
+
+// ValidateAll validates each of values, such as the inputs of a stream,
+// recording which one each violation belongs to.
+func (val *Validator) ValidateAll(values []any) []Violation {
+	var out []Violation
+	for i, v := range values {
+		for _, violation := range val.Validate(v) {
+			violation.Index = i
+			out = append(out, violation)
+		}
+	}
+	return out
+}
+
+// schemaInstance converts the numbers gojq produces (int, *big.Int) into
+// json.Number, which the validator understands. Decoded input already uses
+// json.Number and is returned without copying.
+func schemaInstance(v any) any {
+	if !hasGoNumbers(v) {
+		return v
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			out[k] = schemaInstance(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, child := range val {
+			out[i] = schemaInstance(child)
+		}
+		return out
+	case int:
+		return json.Number(strconv.Itoa(val))
+	case *big.Int:
+		return json.Number(val.String())
+	}
+	return v
+}
+
+func hasGoNumbers(v any) bool {
+	switch val := v.(type) {
+	case map[string]any:
+		for _, child := range val {
+			if hasGoNumbers(child) {
+				return true
+			}
+		}
+	case []any:
+		for _, child := range val {
+			if hasGoNumbers(child) {
+				return true
+			}
+		}
+	case int, *big.Int:
+		return true
+	}
+	return false
+}
+
+// pointerPath turns a JSON pointer into a jq path, using doc to tell array
+// indexes from object keys that look like numbers.
+func pointerPath(doc any, pointer string) []any {
+	if pointer == "" {
+		return nil
+	}
+	var path []any
+	cur := doc
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch val := cur.(type) {
+		case []any:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(val) {
+				path = append(path, i)
+				cur = val[i]
+				continue
+			}
+			cur = nil
+		case map[string]any:
+			cur = val[token]
+		default:
+			cur = nil
+		}
+		path = append(path, token)
+	}
+	return path
+}
@@ -0,0 +1,90 @@
+package jq
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSchema(t *testing.T, text string) *Validator {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	v := writeSchema(t, `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"tags": {"type": "object", "additionalProperties": {"type": "integer"}},
+			"items": {"type": "array", "items": {"properties": {"id": {"type": "integer"}}}}
+		}
+	}`)
+
+	values, err := DecodeValues([]byte(`{"name": 3, "tags": {"0": "x"}, "items": [{"id": 1}, {"id": "two"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, violation := range v.Validate(values[0]) {
+		got[FormatPath(violation.Path)] = violation.Message
+	}
+	// A key that looks like an index stays a key
+	want := []string{".items[1].id", ".name", `.tags."0"`}
+	var paths []string
+	for _, p := range want {
+		if _, ok := got[p]; ok {
+			paths = append(paths, p)
+		}
+	}
+	if !reflect.DeepEqual(paths, want) || len(got) != len(want) {
+		t.Fatalf("violations = %v, want paths %v", got, want)
+	}
+	if !strings.Contains(got[".name"], "want string") {
+		t.Errorf(".name message = %q", got[".name"])
+	}
+
+	if violations := v.Validate(map[string]any{"name": "ok"}); violations != nil {
+		t.Errorf("valid value reported %+v", violations)
+	}
+}
+
+func TestValidateAllResults(t *testing.T) {
+	v := writeSchema(t, `{"type": "integer", "maximum": 10}`)
+
+	// Results from gojq use Go numbers rather than json.Number
+	violations := v.ValidateAll([]any{3, 42, "x"})
+	if len(violations) != 2 {
+		t.Fatalf("violations = %+v, want 2", violations)
+	}
+	if violations[0].Index != 1 || violations[1].Index != 2 {
+		t.Errorf("indexes = %d, %d, want 1, 2", violations[0].Index, violations[1].Index)
+	}
+	if violations[0].Path != nil {
+		t.Errorf("root path = %v, want empty", violations[0].Path)
+	}
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	if _, err := LoadSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing schema should fail")
+	}
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte(`{"type": 5}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSchema(path); err == nil {
+		t.Error("invalid schema should fail")
+	}
+}
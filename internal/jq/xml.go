@@ -0,0 +1,94 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XML elements become objects keyed by child element name. Attributes are
+// prefixed with "@", text alongside attributes or children is kept under
+// "#text", and repeated children collect into an array. Elements holding
+// only text become strings, and empty ones null.
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+)
+
+type xmlElement struct {
+	name     string
+	fields   map[string]any
+	repeated map[string]bool // Children seen more than once, already arrays
+	text     strings.Builder
+}
+
+func (e *xmlElement) addChild(name string, v any) {
+	existing, ok := e.fields[name]
+	switch {
+	case !ok:
+		e.fields[name] = v
+	case e.repeated[name]:
+		e.fields[name] = append(existing.([]any), v)
+	default:
+		e.fields[name] = []any{existing, v}
+		e.repeated[name] = true
+	}
+}
+
+func (e *xmlElement) value() any {
+	text := strings.TrimSpace(e.text.String())
+	if len(e.fields) == 0 {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+	if text != "" {
+		e.fields[xmlTextKey] = text
+	}
+	return e.fields
+}
+
+// decodeXML decodes an XML document as a single object keyed by the root
+// element's name.
+func decodeXML(data []byte) ([]any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// Feeds in the wild declare all sorts of encodings; pass the bytes through
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+
+	root := &xmlElement{fields: map[string]any{}, repeated: map[string]bool{}}
+	stack := []*xmlElement{root}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: t.Name.Local, fields: map[string]any{}, repeated: map[string]bool{}}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				el.fields[xmlAttrPrefix+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1].addChild(el.name, el.value())
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		}
+	}
+	if len(root.fields) == 0 {
+		return nil, errors.New("invalid XML: no root element")
+	}
+	return []any{root.fields}, nil
+}
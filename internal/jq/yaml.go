@@ -0,0 +1,315 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML decodes a stream of YAML documents, one input per document.
+// Documents are walked as nodes rather than decoded into any so that the
+// values match what the JSON decoder produces: string keys, integers as
+// exact json.Numbers, and timestamps left as the strings they were written as.
+func decodeYAML(data []byte) ([]any, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var inputs []any
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return inputs, nil
+		}
+		if err == nil {
+			var v any
+			v, err = newYAMLWalker().value(&node)
+			if err == nil {
+				inputs = append(inputs, v)
+				continue
+			}
+		}
+		if len(inputs) > 0 {
+			return nil, fmt.Errorf("invalid YAML in document %d: %w", len(inputs)+1, err)
+		}
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+}
+
+// yamlWalker converts the nodes of one document. Decoding into nodes skips
+// yaml.v3's alias checks, so the walker repeats them: an alias may not
+// refer to a node it is inside, and documents built mostly from alias
+// expansions (billion laughs) are rejected at the same ratio yaml.v3 uses.
+type yamlWalker struct {
+	expanding  map[*yaml.Node]bool // Anchored nodes whose aliases are being expanded
+	nodes      int                 // Nodes converted
+	aliasNodes int                 // Nodes converted inside an alias expansion
+}
+
+func newYAMLWalker() *yamlWalker {
+	return &yamlWalker{expanding: map[*yaml.Node]bool{}}
+}
+
+// yamlAliasRatio is the share of nodes that may come from alias expansion
+// in a document of n nodes, as in yaml.v3.
+func yamlAliasRatio(n int) float64 {
+	const low, high = 400000, 4000000
+	switch {
+	case n <= low:
+		return 0.99
+	case n >= high:
+		return 0.10
+	}
+	return 0.99 - 0.89*float64(n-low)/float64(high-low)
+}
+
+// count records converting a node, failing on excessive aliasing.
+func (w *yamlWalker) count() error {
+	w.nodes++
+	if len(w.expanding) > 0 {
+		w.aliasNodes++
+	}
+	if w.aliasNodes > 100 && w.nodes > 1000 && float64(w.aliasNodes)/float64(w.nodes) > yamlAliasRatio(w.nodes) {
+		return errors.New("document contains excessive aliasing")
+	}
+	return nil
+}
+
+// resolve returns the node an alias refers to and a function ending its
+// expansion, failing when the alias is inside its own anchor.
+func (w *yamlWalker) resolve(n *yaml.Node) (*yaml.Node, func(), error) {
+	if n.Kind != yaml.AliasNode {
+		return n, func() {}, nil
+	}
+	if w.expanding[n.Alias] {
+		return nil, nil, fmt.Errorf("line %d: alias *%s refers to a node containing it", n.Line, n.Value)
+	}
+	w.expanding[n.Alias] = true
+	return n.Alias, func() { delete(w.expanding, n.Alias) }, nil
+}
+
+func (w *yamlWalker) value(n *yaml.Node) (any, error) {
+	if err := w.count(); err != nil {
+		return nil, err
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return w.value(n.Content[0])
+	case yaml.AliasNode:
+		target, done, err := w.resolve(n)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		return w.value(target)
+	case yaml.SequenceNode:
+		arr := make([]any, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := w.value(c)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case yaml.MappingNode:
+		return w.mapping(n)
+	case yaml.ScalarNode:
+		return yamlScalar(n)
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", n.Line)
+}
+
+// mapping converts a mapping, applying << merge keys. Keys written in the
+// mapping itself win over merged ones.
+func (w *yamlWalker) mapping(n *yaml.Node) (map[string]any, error) {
+	obj := make(map[string]any, len(n.Content)/2)
+	var merged []map[string]any
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind == yaml.ScalarNode && k.ShortTag() == "!!merge" {
+			maps, err := w.mergeSources(v)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, maps...)
+			continue
+		}
+		key, err := yamlKey(k)
+		if err != nil {
+			return nil, err
+		}
+		val, err := w.value(v)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = val
+	}
+	for _, m := range merged {
+		for k, v := range m {
+			if _, ok := obj[k]; !ok {
+				obj[k] = v
+			}
+		}
+	}
+	return obj, nil
+}
+
+func (w *yamlWalker) mergeSources(n *yaml.Node) ([]map[string]any, error) {
+	n, done, err := w.resolve(n)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if err := w.count(); err != nil {
+		return nil, err
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		m, err := w.mapping(n)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]any{m}, nil
+	case yaml.SequenceNode:
+		// Earlier entries take precedence over later ones
+		var maps []map[string]any
+		for _, c := range n.Content {
+			ms, err := w.mergeSources(c)
+			if err != nil {
+				return nil, err
+			}
+			maps = append(maps, ms...)
+		}
+		return maps, nil
+	}
+	return nil, fmt.Errorf("line %d: << must merge a mapping", n.Line)
+}
+
+func yamlKey(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: only scalar mapping keys are supported", n.Line)
+	}
+	if n.ShortTag() == "!!null" {
+		return "null", nil
+	}
+	return n.Value, nil
+}
+
+func yamlScalar(n *yaml.Node) (any, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int":
+		text := strings.ReplaceAll(n.Value, "_", "")
+		if i, ok := new(big.Int).SetString(text, 0); ok {
+			return json.Number(i.String()), nil
+		}
+		return n.Value, nil
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return n.Value, nil
+}
+
+// writeYAML renders v as a YAML document; compact output uses flow style.
+func writeYAML(buf *bytes.Buffer, v any, compact bool) {
+	node := yamlNode(v)
+	if compact {
+		node.Style = yaml.FlowStyle
+	}
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		buf.WriteString("null")
+		return
+	}
+	enc.Close()
+	buf.WriteString(strings.TrimSuffix(out.String(), "\n"))
+}
+
+// yaml11Bools are plain strings in YAML 1.2 but booleans to YAML 1.1 readers
+// such as the one Kubernetes uses, so they are always quoted on output.
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true,
+	"off": true, "Off": true, "OFF": true,
+}
+
+func yamlString(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	if yaml11Bools[s] {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+func yamlNode(v any) *yaml.Node {
+	switch val := v.(type) {
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if len(val) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		for _, item := range val {
+			n.Content = append(n.Content, yamlNode(item))
+		}
+		return n
+	case map[string]any:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if len(val) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n.Content = append(n.Content, yamlString(k), yamlNode(val[k]))
+		}
+		return n
+	case string:
+		return yamlString(val)
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(val)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+
+	// Numbers use gojq's encoding so they read the same as the JSON output
+	b, err := gojq.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	tag := "!!int"
+	if bytes.ContainsAny(b, ".eE") {
+		tag = "!!float"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(b)}
+}
@@ -0,0 +1,137 @@
+package jq
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestYAMLInput(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/manifests.yaml")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	svc, err := NewServiceWithConfig(data, Config{InputFormat: FormatYAML, Output: OutputOptions{Compact: true}})
+	if err != nil {
+		t.Fatalf("NewServiceWithConfig failed: %v", err)
+	}
+	if got := len(svc.Inputs()); got != 2 {
+		t.Fatalf("got %d documents, want 2", got)
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{"per document", ".kind", "\"Deployment\"\n\"Service\""},
+		{"exact ints", "select(.kind == \"Deployment\") | .metadata.uid", "9007199254740993"},
+		{"merge keys", "select(.kind == \"Deployment\") | .spec.template.metadata.labels", `{"app":"web","tier":"frontend"}`},
+		{"timestamps stay strings", "select(.kind == \"Service\") | .metadata.created", `"2024-05-01T10:00:00Z"`},
+		{"typed scalars", "select(.kind == \"Service\") | .spec.ports[0] | [.port, .name, .enabled, .weight]", `[8080,"on",true,0.5]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := svc.Execute(tt.filter)
+			if result.Error != nil {
+				t.Fatalf("Execute failed: %v", result.Error)
+			}
+			if result.Raw != tt.want {
+				t.Errorf("Raw = %q, want %q", result.Raw, tt.want)
+			}
+		})
+	}
+
+	keys, err := svc.KeysAt(".")
+	if err != nil {
+		t.Fatalf("KeysAt failed: %v", err)
+	}
+	if len(keys) != 4 {
+		t.Errorf("KeysAt(.) = %v, want keys across both documents", keys)
+	}
+}
+
+func TestYAMLInputErrors(t *testing.T) {
+	_, err := NewServiceWithConfig([]byte("a: 1\n---\na: [1, 2\n"), Config{InputFormat: FormatYAML})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid YAML in document 2") {
+		t.Fatalf("err = %v, want error naming document 2", err)
+	}
+}
+
+func TestYAMLAliasLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"self reference", "a: &x\n  b: *x\n", "refers to a node containing it"},
+		{"merge cycle", "a: &x\n  <<: *x\n", "refers to a node containing it"},
+		{"billion laughs", yamlLaughs(9), "excessive aliasing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServiceWithConfig([]byte(tt.doc), Config{InputFormat: FormatYAML})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Repeating an anchor side by side is not a cycle
+	svc, err := NewServiceWithConfig([]byte("a: &x {n: 1}\nb: [*x, *x]\n"), Config{InputFormat: FormatYAML, Output: OutputOptions{Compact: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.Execute(".b").Raw; got != `[{"n":1},{"n":1}]` {
+		t.Fatalf(".b = %s", got)
+	}
+}
+
+// yamlLaughs builds a document whose last key expands to 10^levels strings.
+func yamlLaughs(levels int) string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 lol\n")
+	for i := 1; i <= levels; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+func TestYAMLOutput(t *testing.T) {
+	svc, err := NewService([]byte(`{"name":"web","ports":[80,443],"on":"yes","empty":[],"id":12345678901234567890}`))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   OutputOptions
+		filter string
+		want   string
+	}{
+		{"block", OutputOptions{YAML: true}, ".", "empty: []\nid: 12345678901234567890\nname: web\n\"on\": \"yes\"\nports:\n  - 80\n  - 443"},
+		{"documents", OutputOptions{YAML: true}, ".ports[]", "80\n---\n443"},
+		{"flow", OutputOptions{YAML: true, Compact: true}, ".ports, .name", "[80, 443]\nweb"},
+		{"raw string", OutputOptions{YAML: true, Raw: true}, ".on", "yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.SetOutputOptions(tt.opts)
+			result := svc.Execute(tt.filter)
+			if result.Error != nil {
+				t.Fatalf("Execute failed: %v", result.Error)
+			}
+			if result.Raw != tt.want {
+				t.Errorf("Raw = %q, want %q", result.Raw, tt.want)
+			}
+		})
+	}
+}
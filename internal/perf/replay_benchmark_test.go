@@ -1,9 +1,16 @@
 package perf
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dayangraham/gijq/internal/autocomplete"
 	"github.com/dayangraham/gijq/internal/jq"
@@ -61,6 +68,134 @@ func BenchmarkTypingReplay(b *testing.B) {
 	}
 }
 
+// BenchmarkFirstScreen compares showing the first screen of a large result
+// by formatting every output (as batch mode does) with streaming them into
+// a paged buffer and formatting only what is visible.
+func BenchmarkFirstScreen(b *testing.B) {
+	const screen = 50
+	jqSvc, _ := newServicesForBench(b, 55)
+
+	for _, filter := range []string{".", ".items[]"} {
+		b.Run(filter+"/materialised", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				result := jqSvc.Execute(filter)
+				if result.Error != nil {
+					b.Fatalf("filter failed: %v", result.Error)
+				}
+				lines := strings.Split(result.Raw, "\n")
+				_ = lines[:screen]
+			}
+		})
+
+		b.Run(filter+"/streamed", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				buffer := jqSvc.Stream(ctx, filter)
+				buffer.WaitLines(screen, time.Minute)
+				if got := buffer.Lines(0, screen); len(got) != screen {
+					b.Fatalf("got %d lines, want %d", len(got), screen)
+				}
+				cancel()
+				<-buffer.Done()
+			}
+		})
+	}
+}
+
+// BenchmarkLoad compares reading a 100MB input whole and then decoding it,
+// as gijq used to, with decoding it as it is read. peak-MB is the most heap
+// in use at once.
+func BenchmarkLoad(b *testing.B) {
+	path := benchFile(b, 100)
+
+	b.Run("readall", func(b *testing.B) {
+		b.ReportAllocs()
+		peak := trackPeakHeap(b)
+		for i := 0; i < b.N; i++ {
+			collect(b)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+		peak()
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		peak := trackPeakHeap(b)
+		for i := 0; i < b.N; i++ {
+			collect(b)
+			f, err := os.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, err = jq.NewServiceFromReader(f, jq.Config{})
+			f.Close()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		peak()
+	})
+}
+
+// benchFile returns the file scripts/generate_benchdata.go writes for
+// sizeMB, generating it in a temporary directory when it is missing.
+func benchFile(b *testing.B, sizeMB int) string {
+	b.Helper()
+	path := filepath.Join("..", "..", "testdata", "bench", fmt.Sprintf("synthetic-%dmb.json", sizeMB))
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	path = filepath.Join(b.TempDir(), filepath.Base(path))
+	if err := os.WriteFile(path, syntheticJSON(sizeMB), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// collect frees the previous iteration's values outside the timer, so the
+// peak heap is that of one load.
+func collect(b *testing.B) {
+	b.StopTimer()
+	runtime.GC()
+	b.StartTimer()
+}
+
+// trackPeakHeap samples the heap until the returned function reports the
+// largest size seen.
+func trackPeakHeap(b *testing.B) func() {
+	var peak uint64
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			peak = max(peak, stats.HeapAlloc)
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+		b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+	}
+}
+
 func newServicesForBench(b *testing.B, sizeMB int) (*jq.Service, *autocomplete.Service) {
 	b.Helper()
 
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openBrowse moves focus from the filter to the output cursor.
+func (m Model) openBrowse() (tea.Model, tea.Cmd) {
+	if len(m.result.Values) == 0 {
+		m.status = "No output to browse"
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	m.mode = ModeBrowse
+	m.suggestions = nil
+	m.refreshLinePaths()
+	m.clampCursor()
+	return m, nil
+}
+
+func (m Model) handleBrowseKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case "pgup":
+		m.moveCursor(-m.bodyHeight() / 2)
+	case "pgdown":
+		m.moveCursor(m.bodyHeight() / 2)
+	case "g", "home":
+		m.moveCursor(-m.lineCount())
+	case "G", "end":
+		m.moveCursor(m.lineCount())
+	case "shift+left":
+		m.scrollHorizontal(-8)
+	case "shift+right":
+		m.scrollHorizontal(8)
+	case " ", "enter":
+		m.toggleFoldAtCursor()
+	case "left", "h":
+		m.foldOrParent()
+	case "right", "l":
+		m.unfoldAtCursor()
+	case "E", "C":
+		if m.view == viewTree && m.tree != nil {
+			m.tree.setFolded(m.result.Values, msg.String() == "C")
+			if msg.String() == "C" {
+				m.cursor = 0
+			}
+			return m, m.refreshLines()
+		}
+	case "/":
+		return m.openSearch()
+	case "n":
+		m.stepMatch(1)
+	case "N":
+		m.stepMatch(-1)
+	case "y":
+		return m.copyCursorPath()
+	case "p", "i":
+		return m.insertCursorPath()
+	case "tab", "ctrl+o":
+		m.mode = ModeNormal
+	}
+	return m, nil
+}
+
+// bodyHeight is the number of scrolling rows in the output pane.
+func (m Model) bodyHeight() int {
+	h := m.contentHeight() - m.pinnedLines
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+func (m *Model) moveCursor(delta int) {
+	m.cursor += delta
+	m.clampCursor()
+}
+
+// clampCursor keeps the cursor on a body line and scrolls it into view.
+func (m *Model) clampCursor() {
+	last := m.lineCount() - 1
+	if m.cursor > last {
+		m.cursor = last
+	}
+	if m.cursor < m.pinnedLines {
+		m.cursor = m.pinnedLines
+	}
+
+	row := m.cursor - m.pinnedLines
+	switch {
+	case row < m.output.YOffset:
+		m.setOutputOffset(row)
+	case row >= m.output.YOffset+m.bodyHeight():
+		m.setOutputOffset(row - m.bodyHeight() + 1)
+	}
+}
+
+// cursorTreeLine returns the tree line under the cursor, if the tree view
+// is showing.
+func (m Model) cursorTreeLine() (treeLine, bool) {
+	if m.view != viewTree || m.viewFallback || m.cursor < 0 || m.cursor >= len(m.treeLines) {
+		return treeLine{}, false
+	}
+	return m.treeLines[m.cursor], true
+}
+
+// openingLine finds the line that opens the container with tl's path.
+func (m Model) openingLine(tl treeLine, from int) int {
+	for i := from; i >= 0; i-- {
+		l := m.treeLines[i]
+		if l.fold && !l.closing && len(l.path) == len(tl.path) &&
+			treeKey(l.result, l.path) == treeKey(tl.result, tl.path) {
+			return i
+		}
+	}
+	return from
+}
+
+func (m *Model) toggleFoldAtCursor() {
+	tl, ok := m.cursorTreeLine()
+	if !ok || !tl.fold {
+		return
+	}
+	if tl.closing {
+		m.cursor = m.openingLine(tl, m.cursor)
+	}
+	m.tree.toggle(tl.result, tl.path)
+	m.refreshLines() // Tree lines are searched in place, with no scan to run
+}
+
+// foldOrParent folds an open container, or moves to the enclosing one.
+func (m *Model) foldOrParent() {
+	tl, ok := m.cursorTreeLine()
+	if !ok {
+		return
+	}
+	if tl.fold && !m.tree.folded[treeKey(tl.result, tl.path)] {
+		m.toggleFoldAtCursor()
+		return
+	}
+	if len(tl.path) == 0 {
+		return
+	}
+	parent := treeLine{result: tl.result, path: tl.path[:len(tl.path)-1]}
+	m.cursor = m.openingLine(parent, m.cursor-1)
+	m.clampCursor()
+}
+
+func (m *Model) unfoldAtCursor() {
+	tl, ok := m.cursorTreeLine()
+	if !ok || !tl.fold {
+		return
+	}
+	if m.tree.folded[treeKey(tl.result, tl.path)] {
+		m.toggleFoldAtCursor()
+	}
+}
@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+)
+
+const (
+	defaultEditorHeight = 6
+	minEditorHeight     = 2
+)
+
+// editorKey reports whether key belongs to the multi-line editor rather than
+// the output pane, so the cursor can move between lines and Enter inserts a
+// newline.
+func editorKey(key string) bool {
+	switch key {
+	case "enter", "up", "down", "home", "end":
+		return true
+	}
+	return false
+}
+
+func newEditor() textarea.Model {
+	ta := textarea.New()
+	ta.Prompt = ""
+	ta.Placeholder = "."
+	ta.ShowLineNumbers = true
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.MaxWidth = 0
+	ta.SetHeight(defaultEditorHeight)
+	return ta
+}
+
+// filterValue returns the filter from whichever editor is active.
+func (m Model) filterValue() string {
+	if m.multiline {
+		return m.editor.Value()
+	}
+	return m.filter.Value()
+}
+
+// filterCursor returns the cursor position as a rune offset into filterValue.
+func (m Model) filterCursor() int {
+	if !m.multiline {
+		return m.filter.Position()
+	}
+	lines := strings.Split(m.editor.Value(), "\n")
+	row := m.editor.Line()
+	offset := 0
+	for i := 0; i < row && i < len(lines); i++ {
+		offset += len([]rune(lines[i])) + 1
+	}
+	info := m.editor.LineInfo()
+	return offset + info.StartColumn + info.ColumnOffset
+}
+
+// filterBeforeCursor returns the text autocomplete works from.
+func (m Model) filterBeforeCursor() string {
+	r := []rune(m.filterValue())
+	pos := m.filterCursor()
+	if pos > len(r) {
+		pos = len(r)
+	}
+	return string(r[:pos])
+}
+
+// setFilter replaces the filter and places the cursor at the rune offset.
+func (m *Model) setFilter(value string, cursor int) {
+	if !m.multiline {
+		m.filter.SetValue(value)
+		m.filter.SetCursor(cursor)
+		return
+	}
+
+	m.editor.SetValue(value)
+	row, col := 0, cursor
+	for _, line := range strings.Split(value, "\n") {
+		n := len([]rune(line))
+		if col <= n {
+			break
+		}
+		col -= n + 1
+		row++
+	}
+	// SetValue leaves the cursor on the last line; walk back up to the target.
+	for m.editor.Line() > row {
+		m.editor.CursorUp()
+	}
+	m.editor.SetCursor(col)
+}
+
+// replaceBeforeCursor swaps the text before the cursor for prefix, keeping
+// the rest of the filter and leaving the cursor after prefix.
+func (m *Model) replaceBeforeCursor(prefix string) {
+	r := []rune(m.filterValue())
+	pos := m.filterCursor()
+	if pos > len(r) {
+		pos = len(r)
+	}
+	m.setFilter(prefix+string(r[pos:]), len([]rune(prefix)))
+}
+
+// refreshContext re-parses the autocomplete context at the cursor.
+func (m *Model) refreshContext() {
+	before := m.filterBeforeCursor()
+	m.acContext = m.autocomplete.ParseContext(before)
+	m.refreshFunctions(before)
+}
+
+// refreshFunctions recomputes the functions listed while a function name is
+// typed. Listing them reads imported modules, so View shows the stored list.
+func (m *Model) refreshFunctions(before string) {
+	if m.acContext.Kind != autocomplete.KindFunction {
+		m.functionNames, m.functionsFor = nil, ""
+		return
+	}
+	if before != m.functionsFor {
+		m.functionNames, _ = m.autocomplete.Suggest(before)
+		m.functionsFor = before
+	}
+}
+
+// updateFilterInput forwards a key to the active editor and re-runs the
+// query when the text changed.
+func (m *Model) updateFilterInput(msg tea.Msg) tea.Cmd {
+	before := m.filterValue()
+	var cmd tea.Cmd
+	if m.multiline {
+		m.editor, cmd = m.editor.Update(msg)
+	} else {
+		m.filter, cmd = m.filter.Update(msg)
+	}
+	// Update autocomplete context so Available keys panel stays in sync
+	m.refreshContext()
+	if m.filterValue() == before {
+		return tea.Batch(cmd, m.maybeFetchKeys())
+	}
+	return tea.Batch(cmd, m.queueExecute(), m.maybeFetchKeys())
+}
+
+// programText returns the filter as written: the multi-line program the
+// one-line filter was joined from, comments and layout included, as long as
+// the joined line has not been edited since.
+func (m Model) programText() string {
+	value := m.filterValue()
+	if !m.multiline && m.joinedFrom != "" && singleLineFilter(m.joinedFrom) == value {
+		return m.joinedFrom
+	}
+	return value
+}
+
+// toggleEditor switches between the one-line filter bar and the multi-line
+// editor, carrying the filter across. Switching back to the editor restores
+// the program a one-line filter was joined from unless it was edited.
+func (m Model) toggleEditor() (tea.Model, tea.Cmd) {
+	value := m.programText()
+	m.joinedFrom = ""
+	var cmd tea.Cmd
+	if m.multiline {
+		flat := singleLineFilter(value)
+		if flat != value {
+			m.joinedFrom = value
+		}
+		m.multiline = false
+		m.editor.Blur()
+		m.filter.SetValue(flat)
+		m.filter.CursorEnd()
+		cmd = m.filter.Focus()
+		m.status = "Single-line filter"
+		if flat != value {
+			m.status = "Joined filter onto one line"
+		}
+	} else {
+		m.multiline = true
+		m.filter.Blur()
+		m.editor.SetValue(value)
+		cmd = m.editor.Focus()
+		m.status = "Multi-line editor (alt+enter: output and quit)"
+	}
+	m.refreshContext()
+	m.resizeOutput()
+	return m, tea.Batch(cmd, m.executeNow(), m.maybeFetchKeys(), clearStatusAfter(3*time.Second))
+}
+
+// resizeEditor grows or shrinks the multi-line editor by delta rows.
+func (m *Model) resizeEditor(delta int) {
+	h := m.editor.Height() + delta
+	if h < minEditorHeight {
+		h = minEditorHeight
+	}
+	// Keep a few rows of output visible.
+	if maxH := m.height - 8 - 3; maxH >= minEditorHeight && h > maxH {
+		h = maxH
+	}
+	m.editor.SetHeight(h)
+	m.resizeOutput()
+}
+
+// editorExtraLines is how many more footer rows the editor needs than the
+// one-line filter bar.
+func (m Model) editorExtraLines() int {
+	if !m.multiline {
+		return 0
+	}
+	// Label row plus the editor rows, replacing the single filter row.
+	return m.editor.Height()
+}
+
+// resizeOutput refits the output pane after the footer changed height.
+func (m *Model) resizeOutput() {
+	if !m.ready {
+		return
+	}
+	m.output.Height = m.contentHeight()
+}
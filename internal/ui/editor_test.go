@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorCursor(t *testing.T) {
+	program := "def f: .a;\n.items[] | f\n| .naïve"
+
+	m := Model{editor: newEditor(), multiline: true}
+	m.editor.Focus()
+
+	for _, pos := range []int{0, 4, 10, 11, 18, 24, len([]rune(program))} {
+		m.setFilter(program, pos)
+		if got := m.filterCursor(); got != pos {
+			t.Fatalf("setFilter(%d) put cursor at %d", pos, got)
+		}
+		if got, want := m.filterBeforeCursor(), string([]rune(program)[:pos]); got != want {
+			t.Fatalf("filterBeforeCursor at %d = %q, want %q", pos, got, want)
+		}
+	}
+}
+
+func TestReplaceBeforeCursor(t *testing.T) {
+	m := Model{editor: newEditor(), multiline: true}
+	m.editor.Focus()
+
+	m.setFilter(".it\n| length", 3)
+	m.replaceBeforeCursor(".items")
+
+	if got, want := m.filterValue(), ".items\n| length"; got != want {
+		t.Fatalf("filterValue() = %q, want %q", got, want)
+	}
+	if got := m.filterCursor(); got != 6 {
+		t.Fatalf("filterCursor() = %d, want 6", got)
+	}
+}
+
+func TestToggleEditorKeepsComments(t *testing.T) {
+	program := "# Names of the items\n.n # the count\n| . + 1"
+	m := newStreamModel(t, program)
+	if !m.multiline {
+		t.Fatal("a multi-line filter should open in the editor")
+	}
+
+	updated, _ := m.toggleEditor()
+	m = updated.(Model)
+	if got := m.filterValue(); got != ".n | . + 1" {
+		t.Fatalf("joined filter = %q", got)
+	}
+
+	// Saving the untouched line keeps the program as written
+	path := filepath.Join(t.TempDir(), "q.jq")
+	updated, _ = m.saveFilter(path)
+	m = updated.(Model)
+	if data, err := os.ReadFile(path); err != nil || string(data) != program+"\n" {
+		t.Fatalf("saved %q, %v", data, err)
+	}
+
+	updated, _ = m.toggleEditor()
+	m = updated.(Model)
+	if got := m.filterValue(); got != program {
+		t.Fatalf("toggling back gave %q, want the original program", got)
+	}
+
+	// An edited line replaces the program
+	updated, _ = m.toggleEditor()
+	m = updated.(Model)
+	m.setFilter(".n", 2)
+	updated, _ = m.toggleEditor()
+	m = updated.(Model)
+	if got := m.filterValue(); got != ".n" {
+		t.Fatalf("toggling back after an edit gave %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultExternalEditor = "vi"
+
+type externalEditorMsg struct {
+	path string
+	err  error
+}
+
+// editorCommand builds the command for $VISUAL or $EDITOR, which may carry
+// arguments such as "code --wait".
+func editorCommand(getenv func(string) string, path string) *exec.Cmd {
+	editor := strings.TrimSpace(getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = defaultExternalEditor
+	}
+	args := strings.Fields(editor)
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// openExternalEditor suspends the TUI and edits the filter as a temporary
+// .jq file; the result comes back as an externalEditorMsg. A one-line filter
+// joined from a multi-line program is edited as that program.
+func (m Model) openExternalEditor() (tea.Model, tea.Cmd) {
+	f, err := os.CreateTemp("", "gijq-*.jq")
+	if err == nil {
+		err = writeFilterFile(f.Name(), m.programText())
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		m.status = "Editor failed: " + err.Error()
+		return m, clearStatusAfter(3 * time.Second)
+	}
+
+	path := f.Name()
+	cmd := editorCommand(os.Getenv, path)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return externalEditorMsg{path: path, err: err}
+	})
+}
+
+// applyExternalEdit loads the edited program back into the filter and runs it.
+func (m Model) applyExternalEdit(msg externalEditorMsg) (tea.Model, tea.Cmd) {
+	data, err := os.ReadFile(msg.path)
+	os.Remove(msg.path)
+	if err == nil {
+		err = msg.err
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			m.status = "Editor exited with " + exitErr.ProcessState.String() + "; filter unchanged"
+		} else {
+			m.status = "Editor failed: " + err.Error()
+		}
+		return m, clearStatusAfter(3 * time.Second)
+	}
+
+	program := strings.TrimRight(string(data), "\n")
+	var cmds []tea.Cmd
+	if strings.Contains(program, "\n") && !m.multiline {
+		// Keep the program's layout rather than folding it onto one line
+		m.multiline = true
+		m.filter.Blur()
+		cmds = append(cmds, m.editor.Focus())
+		m.resizeOutput()
+	}
+	if !m.multiline {
+		program = singleLineFilter(program)
+	}
+	m.setFilter(program, len([]rune(program)))
+	m.joinedFrom = ""
+	m.refreshContext()
+	m.status = "Reloaded filter from editor"
+	cmds = append(cmds, m.executeNow(), m.maybeFetchKeys(), clearStatusAfter(3*time.Second))
+	return m, tea.Batch(cmds...)
+}
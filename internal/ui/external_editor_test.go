@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/history"
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{name: "default", env: map[string]string{}, want: []string{"vi", "f.jq"}},
+		{name: "editor", env: map[string]string{"EDITOR": "nano"}, want: []string{"nano", "f.jq"}},
+		{name: "visual wins", env: map[string]string{"EDITOR": "nano", "VISUAL": "hx"}, want: []string{"hx", "f.jq"}},
+		{name: "with args", env: map[string]string{"EDITOR": "code --wait"}, want: []string{"code", "--wait", "f.jq"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := editorCommand(func(k string) string { return tt.env[k] }, "f.jq")
+			if !reflect.DeepEqual(cmd.Args, tt.want) {
+				t.Fatalf("editorCommand() args = %q, want %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyExternalEdit(t *testing.T) {
+	jqSvc, err := jq.NewService([]byte(`{"items":[{"id":1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist, err := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(jqSvc, autocomplete.NewService(jqSvc), hist, nil, Config{})
+
+	path := filepath.Join(t.TempDir(), "edit.jq")
+	if err := os.WriteFile(path, []byte("# ids\n.items[]\n| .id\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, _ := m.applyExternalEdit(externalEditorMsg{path: path})
+	got := updated.(Model)
+	if !got.multiline {
+		t.Fatal("multi-line program did not open the multi-line editor")
+	}
+	if want := "# ids\n.items[]\n| .id"; got.filterValue() != want {
+		t.Fatalf("filterValue() = %q, want %q", got.filterValue(), want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("temp file not removed: %v", err)
+	}
+}
+
+func TestExternalEditorGetsJoinedProgram(t *testing.T) {
+	program := "# the count\n.n\n| . + 1"
+	m := newStreamModel(t, program)
+	updated, _ := m.toggleEditor()
+	m = updated.(Model)
+
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	if _, cmd := m.openExternalEditor(); cmd == nil {
+		t.Fatal("no editor command")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "gijq-*.jq"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("temp files = %v, %v", paths, err)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil || string(data) != program+"\n" {
+		t.Fatalf("editor got %q, %v", data, err)
+	}
+
+	// The edited one-line filter no longer stands for the joined program
+	if err := os.WriteFile(paths[0], []byte(".n | . + 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ = m.applyExternalEdit(externalEditorMsg{path: paths[0]})
+	m = updated.(Model)
+	if m.multiline || m.joinedFrom != "" || m.programText() != ".n | . + 2" {
+		t.Fatalf("multiline = %v, joinedFrom = %q, program = %q", m.multiline, m.joinedFrom, m.programText())
+	}
+}
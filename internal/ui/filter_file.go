@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// singleLineFilter joins a multi-line jq program so it fits the one-line
+// filter bar. Comments run to the end of the line in jq, so they are dropped
+// rather than allowed to swallow the rest of the program.
+func singleLineFilter(program string) string {
+	if !strings.ContainsAny(program, "\r\n") {
+		return program
+	}
+
+	var b strings.Builder
+	inString, escaped, inComment := false, false, false
+	lastSpace := true // Suppresses leading whitespace
+	// Open parens in each enclosing \( ) interpolation, innermost last.
+	// Strings inside an interpolation nest within the outer string.
+	var interp []int
+	for _, r := range program {
+		if inComment {
+			if r != '\n' {
+				continue
+			}
+			inComment = false
+		}
+		if inString {
+			switch {
+			case escaped && r == '(':
+				escaped = false
+				inString = false
+				interp = append(interp, 0)
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			b.WriteRune(r)
+			continue
+		}
+		switch r {
+		case '#':
+			inComment = true
+			continue
+		case '"':
+			inString = true
+		case '(':
+			if len(interp) > 0 {
+				interp[len(interp)-1]++
+			}
+		case ')':
+			if n := len(interp); n > 0 {
+				if interp[n-1] == 0 {
+					interp = interp[:n-1]
+					inString = true
+				} else {
+					interp[n-1]--
+				}
+			}
+		case '\n', '\r', '\t', ' ':
+			if lastSpace {
+				continue
+			}
+			b.WriteByte(' ')
+			lastSpace = true
+			continue
+		}
+		b.WriteRune(r)
+		lastSpace = false
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// filterFilePath normalises a save target, defaulting to a .jq extension.
+func filterFilePath(path string) string {
+	path = strings.TrimSpace(path)
+	if path != "" && filepath.Ext(path) == "" {
+		path += ".jq"
+	}
+	return path
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeFilterFile saves filter as a jq program file.
+func writeFilterFile(path, filter string) error {
+	if path == "" {
+		return errors.New("no file name")
+	}
+	if !strings.HasSuffix(filter, "\n") {
+		filter += "\n"
+	}
+	return os.WriteFile(path, []byte(filter), 0o644)
+}
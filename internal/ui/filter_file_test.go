@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSingleLineFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "already single line", in: `.a |  .b`, want: `.a |  .b`},
+		{name: "joins lines", in: ".items[]\n  | select(.ok)\n", want: ".items[] | select(.ok)"},
+		{name: "drops comments", in: "# pick items\n.items[] # all of them\n| .id", want: ".items[] | .id"},
+		{name: "keeps hash in strings", in: ".tags[]\n| select(. == \"#ops  team\")", want: `.tags[] | select(. == "#ops  team")`},
+		{name: "escaped quote", in: "\"a\\\"#b\"\n| length", want: `"a\"#b" | length`},
+		{name: "hash in interpolated string", in: "\"\\(\"#x\")\" # note\n| length", want: `"\("#x")" | length`},
+		{name: "nested interpolation", in: "\"a\\((.b | tostring) + \"\\(\"#\")\")#c\"\n| .", want: `"a\((.b | tostring) + "\("#")")#c" | .`},
+		{name: "comment in interpolation", in: "\"\\(.a # pick a\n)#\"\n| .", want: `"\(.a )#" | .`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := singleLineFilter(tt.in); got != tt.want {
+				t.Fatalf("singleLineFilter(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "query")
+	path = filterFilePath(path)
+	if filepath.Ext(path) != ".jq" {
+		t.Fatalf("filterFilePath did not add .jq: %q", path)
+	}
+	if fileExists(path) {
+		t.Fatal("fileExists = true before save")
+	}
+	if err := writeFilterFile(path, ".items[]"); err != nil {
+		t.Fatalf("writeFilterFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != ".items[]\n" {
+		t.Fatalf("saved %q, want %q", data, ".items[]\n")
+	}
+	if !fileExists(path) {
+		t.Fatal("fileExists = false after save")
+	}
+}
@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// maxFindMatches caps the paths listed for one lookup.
+const maxFindMatches = 500
+
+// findMsg carries the paths matching a find-value query.
+type findMsg struct {
+	seq     int
+	matches []jq.PathMatch
+}
+
+func (m Model) openFind() (tea.Model, tea.Cmd) {
+	if m.jq == nil || m.jq.NullInput() {
+		m.status = "No input to search"
+		return m, nil
+	}
+	if m.loading {
+		m.status = "The input is still loading"
+		return m, nil
+	}
+	ti := textinput.New()
+	ti.Prompt = "find: "
+	ti.Placeholder = "key or value (regex)"
+	ti.CharLimit = 0
+	ti.Width = 40
+	ti.SetValue(m.findQuery)
+	ti.CursorEnd()
+	ti.Focus()
+
+	m.findInput = ti
+	m.mode = ModeFind
+	m.suggestions = nil
+	return m, textinput.Blink
+}
+
+func (m Model) handleFindKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.mode = ModeNormal
+		return m, nil
+
+	case "up", "ctrl+p":
+		if m.findIdx > 0 {
+			m.findIdx--
+		}
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.findIdx < min(len(m.findMatches), maxFindMatches)-1 {
+			m.findIdx++
+		}
+		return m, nil
+
+	case "enter":
+		query := strings.TrimSpace(m.findInput.Value())
+		if query == "" {
+			return m, nil
+		}
+		// A new query runs the lookup; otherwise Enter picks the selection
+		if query != m.findQuery || m.findRunning {
+			return m, m.startFind(query)
+		}
+		if len(m.findMatches) == 0 {
+			return m, nil
+		}
+		return m.selectFindMatch(m.findMatches[m.findIdx])
+
+	default:
+		var cmd tea.Cmd
+		m.findInput, cmd = m.findInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// startFind walks the input in the background for paths matching query.
+func (m *Model) startFind(query string) tea.Cmd {
+	m.findSeq++
+	m.findQuery = query
+	m.findMatches = nil
+	m.findLabels = nil
+	m.findIdx = 0
+	m.findRunning = true
+
+	seq, data, re := m.findSeq, m.jq.Data(), compileSearch(query)
+	return func() tea.Msg {
+		return findMsg{seq: seq, matches: jq.FindPaths(data, re, maxFindMatches+1)}
+	}
+}
+
+func (m Model) applyFind(msg findMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.findSeq {
+		return m, nil
+	}
+	m.findRunning = false
+	m.findMatches = msg.matches
+	m.findLabels = make([]string, min(len(msg.matches), maxFindMatches))
+	for i := range m.findLabels {
+		m.findLabels[i] = m.findMatchLabel(msg.matches[i])
+	}
+	m.findIdx = 0
+	return m, nil
+}
+
+// selectFindMatch replaces the filter with the path of match.
+func (m Model) selectFindMatch(match jq.PathMatch) (tea.Model, tea.Cmd) {
+	_, path := m.findLocation(match)
+	filter := jq.FormatPath(path)
+	m.setFilter(filter, len([]rune(filter)))
+	m.mode = ModeNormal
+	m.refreshContext()
+	return m, tea.Batch(m.executeNow(), m.maybeFetchKeys())
+}
+
+// findLocation splits a match into the stream input it came from (or -1)
+// and the path within that input, which is what a filter sees.
+func (m Model) findLocation(match jq.PathMatch) (int, []any) {
+	if !m.jq.IsStream() || len(match.Path) == 0 {
+		return -1, match.Path
+	}
+	input, _ := match.Path[0].(int)
+	return input, match.Path[1:]
+}
+
+// findMatchLabel describes a match for the list: its path and a preview of
+// the value found there, which for a key may be a whole subtree.
+func (m Model) findMatchLabel(match jq.PathMatch) string {
+	input, path := m.findLocation(match)
+	label := jq.FormatPath(path)
+	if input >= 0 {
+		label = fmt.Sprintf("[%d] %s", input+1, label)
+	}
+	preview := previewValue(match.Value)
+	if match.Key {
+		preview = "key · " + preview
+	}
+	return label + "  " + preview
+}
+
+func (m Model) overlayFind(base string) string {
+	lines := []string{
+		titleStyle.Render("Find Value"),
+		helpStyle.Render("enter: search, then select | ↑/↓: navigate | esc: close"),
+		"",
+		m.findInput.View(),
+		"",
+	}
+
+	width := m.width - 12
+	if width < 20 {
+		width = 20
+	}
+	const maxShow = 10
+	switch {
+	case m.findRunning:
+		lines = append(lines, helpStyle.Render("Searching..."))
+	case m.findQuery == "":
+		lines = append(lines, helpStyle.Render("Matches keys and scalar values anywhere in the input"))
+	case len(m.findMatches) == 0:
+		lines = append(lines, helpStyle.Render("No paths match"))
+	default:
+		count := fmt.Sprintf("%d paths", len(m.findMatches))
+		if len(m.findMatches) > maxFindMatches {
+			count = fmt.Sprintf("first %d paths", maxFindMatches)
+		}
+		lines = append(lines, helpStyle.Render(count))
+
+		// Keep the selection inside the window of shown matches
+		first := 0
+		if m.findIdx >= maxShow {
+			first = m.findIdx - maxShow + 1
+		}
+		for i := first; i < len(m.findMatches) && i < first+maxShow && i < maxFindMatches; i++ {
+			item := []rune(m.findLabels[i])
+			if trimmed := trimToDisplayWidth(item, width); len(trimmed) < len(item) {
+				item = append(trimToDisplayWidth(trimmed, width-1), '…')
+			}
+			if i == m.findIdx {
+				lines = append(lines, selectedStyle.Render("→ "+string(item)))
+			} else {
+				lines = append(lines, suggestionStyle.Render("  "+string(item)))
+			}
+		}
+	}
+
+	overlay := historyOverlayStyle.Render(strings.Join(lines, "\n"))
+	return placeOverlay(base, overlay, m.width, m.height)
+}
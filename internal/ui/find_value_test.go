@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/history"
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestFindValueSetsFilter(t *testing.T) {
+	svc, err := jq.NewService([]byte(`{"orders":[{"id":"A-1"},{"id":"B-2","buyer":{"email":"x@y.z"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist, err := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{})
+
+	updated, _ := m.openFind()
+	m = updated.(Model)
+	for _, r := range "x@y" {
+		updated, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(Model)
+	}
+
+	// The first Enter runs the lookup
+	updated, cmd := m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	if len(m.findMatches) != 1 {
+		t.Fatalf("matches = %+v, want 1", m.findMatches)
+	}
+	if want := `.orders[1].buyer.email  "x@y.z"`; len(m.findLabels) != 1 || m.findLabels[0] != want {
+		t.Fatalf("labels = %q, want %q", m.findLabels, want)
+	}
+
+	// The second picks the selected path
+	updated, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if got := m.filterValue(); got != ".orders[1].buyer.email" {
+		t.Fatalf("filter = %q", got)
+	}
+	if m.mode != ModeNormal {
+		t.Fatalf("mode = %v, want normal", m.mode)
+	}
+}
+
+func TestFindLabelsSummariseSubtrees(t *testing.T) {
+	svc, err := jq.NewService([]byte(`{"items":[1,2,3]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Model{jq: svc, findSeq: 1}
+	updated, _ := m.applyFind(findMsg{seq: 1, matches: []jq.PathMatch{
+		{Path: []any{"items"}, Value: []any{1, 2, 3}, Key: true},
+	}})
+	m = updated.(Model)
+	if want := ".items  key · […] 3 items"; m.findLabels[0] != want {
+		t.Fatalf("label = %q, want %q", m.findLabels[0], want)
+	}
+}
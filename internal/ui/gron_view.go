@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// gronLines renders the results as gron statements, or with ungron on, as
+// the JSON rebuilt from the statements the ungron pattern kept.
+func (m *Model) gronLines() []string {
+	stmts := jq.GronStatements(m.result.Values)
+	m.gronTotal = len(stmts)
+	if !m.ungron {
+		lines := make([]string, len(stmts))
+		for i, s := range stmts {
+			lines[i] = s.String()
+		}
+		return lines
+	}
+
+	kept := stmts
+	if m.ungronRe != nil {
+		kept = nil
+		for _, s := range stmts {
+			if m.ungronRe.MatchString(s.String()) {
+				kept = append(kept, s)
+			}
+		}
+	}
+	m.ungronKept = len(kept)
+	v, err := jq.UngronStatements(kept)
+	if err != nil {
+		return []string{"ungron: " + err.Error()}
+	}
+	m.ungronValue = v
+	return strings.Split(jq.FormatValue(v, m.ungronOptions()), "\n")
+}
+
+// ungronOptions formats rebuilt JSON with the current options, minus the
+// ones that replace JSON altogether.
+func (m Model) ungronOptions() jq.OutputOptions {
+	var opts jq.OutputOptions
+	if m.jq != nil {
+		opts = m.jq.OutputOptions()
+	}
+	opts.Gron, opts.Table = false, ""
+	return opts
+}
+
+// toggleUngron switches the gron view between the statements and the JSON
+// they rebuild. An active search picks the statements kept, the way gron
+// output is piped through grep before gron -u.
+func (m Model) toggleUngron() (tea.Model, tea.Cmd) {
+	if m.view != viewGron {
+		m.status = "Ungron works on the gron view (alt+g)"
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	m.ungron = !m.ungron
+	m.ungronRe = m.searchRe
+	m.outputXOffset = 0
+	m.cursor = 0
+	scan := m.refreshLines()
+	m.output.YOffset = 0
+
+	switch {
+	case !m.ungron:
+		m.status = "View: gron"
+	case m.ungronRe != nil:
+		m.status = fmt.Sprintf("Ungron: %d of %d statements matching /%s/", m.ungronKept, m.gronTotal, m.searchText)
+	default:
+		m.status = "Ungron: all statements"
+	}
+	return m, tea.Batch(scan, clearStatusAfter(3*time.Second))
+}
+
+// gronLinePaths maps gron statements to the values they assign.
+func gronLinePaths(values []any) []linePath {
+	stmts := jq.GronStatements(values)
+	paths := make([]linePath, len(stmts))
+	for i, s := range stmts {
+		paths[i] = resultPath(values, s.Path)
+	}
+	return paths
+}
+
+// resultPath splits a path over the results, where several results are
+// addressed like an array, into the result and the path within it.
+func resultPath(values []any, path []any) linePath {
+	if len(values) == 1 {
+		return linePath{path: path, ok: true}
+	}
+	if len(path) == 0 {
+		return linePath{}
+	}
+	result, _ := path[0].(int)
+	return linePath{result: result, path: path[1:], ok: true}
+}
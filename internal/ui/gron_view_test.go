@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestGronView(t *testing.T) {
+	m := Model{
+		view: viewGron,
+		mode: ModeBrowse,
+		result: jq.Result{Values: []any{map[string]any{
+			"a": json.Number("1"),
+			"b": []any{"x", "y"},
+		}}},
+	}
+	m.refreshLines()
+
+	want := []string{`json = {};`, `json.a = 1;`, `json.b = [];`, `json.b[0] = "x";`, `json.b[1] = "y";`}
+	if !equalStringSlices(m.lines, want) {
+		t.Fatalf("gron lines = %q, want %q", m.lines, want)
+	}
+	if lp, _ := m.linePathAt(3); jq.FormatPath(lp.path) != ".b[0]" {
+		t.Fatalf("line 3 path = %s, want .b[0]", jq.FormatPath(lp.path))
+	}
+
+	// Ungron keeps the statements matching the search
+	m.setSearch(`b\[1\]`)
+	updated, _ := m.toggleUngron()
+	m = updated.(Model)
+	want = []string{"{", `  "b": [`, "    null,", `    "y"`, "  ]", "}"}
+	if !equalStringSlices(m.lines, want) {
+		t.Fatalf("ungron lines = %q, want %q", m.lines, want)
+	}
+	if want := `Ungron: 1 of 5 statements matching /b\[1\]/`; m.status != want {
+		t.Fatalf("status = %q, want %q", m.status, want)
+	}
+	if lp, _ := m.linePathAt(3); jq.FormatPath(lp.path) != ".b[1]" {
+		t.Fatalf("ungron line 3 path = %s, want .b[1]", jq.FormatPath(lp.path))
+	}
+
+	updated, _ = m.toggleUngron()
+	m = updated.(Model)
+	if len(m.lines) != 5 {
+		t.Fatalf("after toggling back got %d lines, want 5", len(m.lines))
+	}
+}
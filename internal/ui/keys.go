@@ -1,16 +1,16 @@
 package ui
 
 import (
-	"context"
-	"errors"
-	"fmt"
-	"os"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/jq"
 )
 
 // Update handles messages
@@ -22,6 +22,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.editor.SetWidth(m.width)
 		if !m.ready {
 			m.output = newViewport(m.width, m.contentHeight())
 			m.ready = true
@@ -33,32 +34,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case resultMsg:
-		m.telemetry.OnResult(msg.seq, msg.result.Error, msg.seq == m.activeQuerySeq)
-		if msg.seq != m.activeQuerySeq {
-			return m, nil
-		}
-		m.queryRunning = false
-		m.queryCancel = nil
-		if errors.Is(msg.result.Error, context.Canceled) {
-			return m, nil
-		}
-		m.result = msg.result
-		if msg.result.Error != nil {
-			m.lines = strings.Split(msg.result.Error.Error(), "\n")
-		} else {
-			m.lines = strings.Split(msg.result.Raw, "\n")
-		}
-		m.maxLineWidth = maxDisplayLineWidth(m.lines)
-		m.clampOutputXOffset()
-		if m.ready {
-			// Set raw content for viewport scrolling calculation
-			if msg.result.Error != nil {
-				m.output.SetContent(msg.result.Error.Error())
-			} else {
-				m.output.SetContent(msg.result.Raw)
-			}
-		}
-		return m, nil
+		return m.applyResults(msg)
 
 	case executeQueryMsg:
 		if msg.seq != m.querySeq {
@@ -82,6 +58,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.availableKeys = msg.keys
 		return m, nil
 
+	case externalEditorMsg:
+		return m.applyExternalEdit(msg)
+
+	case findMsg:
+		return m.applyFind(msg)
+
+	case searchChunkMsg:
+		return m.applySearchChunk(msg)
+
+	case inputViolationsMsg:
+		m.inputValidated = true
+		m.inputViolations = msg.violations
+		return m, nil
+
+	case resultViolationsMsg:
+		return m.applyResultViolations(msg)
+
+	case inputLoadedMsg:
+		return m.applyInputLoaded(msg)
+
+	case loadTickMsg:
+		if m.loading {
+			return m, loadTick()
+		}
+		return m, nil
+
 	case statusClearMsg:
 		m.status = ""
 		return m, nil
@@ -93,6 +95,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	// The save prompt takes free text, so it bypasses the global shortcuts
+	if m.mode == ModeSave {
+		return m.handleSaveKey(msg)
+	}
+	if m.mode == ModeSearch {
+		return m.handleSearchKey(msg)
+	}
+	if m.mode == ModeFind {
+		return m.handleFindKey(msg)
+	}
+
 	// Global keys
 	switch key {
 	case "ctrl+c":
@@ -115,11 +128,76 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case "ctrl+y":
+		if m.view == viewSchema {
+			return m.copySchema()
+		}
 		return m.copyOutput()
 
 	case "ctrl+f":
 		return m.copyFilter()
 
+	case "ctrl+x":
+		if m.result.Error != nil || len(m.result.Values) == 0 {
+			m.status = "Nothing to export"
+			return m, clearStatusAfter(3 * time.Second)
+		}
+		m.mode = ModeExport
+		m.exportIdx = 0
+		return m, nil
+
+	case "ctrl+s":
+		return m.openSavePrompt()
+
+	case "alt+r", "alt+c", "alt+i", "alt+y":
+		return m.toggleOutputOption(key)
+
+	case "alt+t":
+		return m.toggleView(viewTable)
+
+	case "alt+o":
+		return m.toggleView(viewTree)
+
+	case "alt+k":
+		return m.toggleView(viewSchema)
+
+	case "alt+g":
+		return m.toggleView(viewGron)
+
+	case "alt+u":
+		return m.toggleUngron()
+
+	case "ctrl+o":
+		if m.mode == ModeBrowse {
+			m.mode = ModeNormal
+			return m, nil
+		}
+		return m.openBrowse()
+
+	case "alt+/":
+		return m.openSearch()
+
+	case "alt+p":
+		return m.openFind()
+
+	case "alt+v":
+		return m.openViolations()
+
+	case "alt+m":
+		return m.toggleEditor()
+
+	case "alt+e":
+		return m.openExternalEditor()
+
+	case "alt+up", "alt+down":
+		if m.multiline {
+			delta := 1
+			if key == "alt+up" {
+				delta = -1
+			}
+			m.resizeEditor(delta)
+		}
+		return m, nil
+
 	case "ctrl+h":
 		m.mode = ModeHistory
 		m.historyItems = m.history.Get(m.filepath)
@@ -137,6 +215,12 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleHistoryKey(msg)
 	case ModeHelp:
 		return m.handleHelpKey(msg)
+	case ModeExport:
+		return m.handleExportKey(msg)
+	case ModeBrowse:
+		return m.handleBrowseKey(msg)
+	case ModeViolations:
+		return m.handleViolationsKey(msg)
 	}
 
 	return m, nil
@@ -145,56 +229,70 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleNormalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	// In the multi-line editor Enter and the arrows edit the program;
+	// alt+enter takes over emitting the result.
+	if m.multiline && editorKey(key) {
+		return m, m.updateFilterInput(msg)
+	}
+
 	switch key {
-	case "enter":
-		// Output result and quit
-		if m.result.Error == nil && m.result.Raw != "" {
-			fmt.Fprint(os.Stdout, m.result.Raw)
+	case "enter", "alt+enter":
+		// Output result and quit; main prints it once the TUI has exited
+		if m.buffer != nil && m.result.Error == nil && len(m.result.Values) > 0 {
+			m.emitted = true
+			m.emitFilter = m.filterValue()
+			if m.buffer.Finished() && !m.buffer.Truncated() {
+				m.emitBuffer = m.buffer
+			}
+			if m.queryCancel != nil {
+				m.queryCancel()
+			}
 			// Save to history
-			m.history.Add(m.filepath, m.filter.Value())
+			m.history.Add(m.filepath, m.filterValue())
 			m.history.Save()
 		}
 		return m, tea.Quit
 
 	case "tab":
 		m.mode = ModeAutocomplete
-		filter := m.filter.Value()
+		// Complete from the text before the cursor, keeping whatever follows
+		filter := m.filterBeforeCursor()
 
 		// If filter ends with ], append . to drill into sub-keys
 		if strings.HasSuffix(filter, "]") {
 			filter = filter + "."
-			m.filter.SetValue(filter)
-			m.filter.SetCursor(len(filter))
+			m.replaceBeforeCursor(filter)
 		}
 
 		m.suggestions, m.acContext = m.autocomplete.Suggest(filter)
 		m.selectedIdx = 0
 
 		// If only suggestion exactly matches what's typed, drill deeper
-		if len(m.suggestions) == 1 && m.suggestions[0] == m.acContext.Incomplete && m.acContext.Incomplete != "" {
+		if m.acContext.Kind == autocomplete.KindKey &&
+			len(m.suggestions) == 1 && m.suggestions[0] == m.acContext.Incomplete && m.acContext.Incomplete != "" {
 			newFilter := filter[:m.acContext.StartPos] + m.suggestions[0] + "."
-			m.filter.SetValue(newFilter)
-			m.filter.SetCursor(len(newFilter))
+			m.replaceBeforeCursor(newFilter)
 			m.suggestions, m.acContext = m.autocomplete.Suggest(newFilter)
 			m.selectedIdx = 0
 		}
+		m.refreshFunctions(m.filterBeforeCursor())
 
 		return m, nil
 
 	case "up":
-		m.output.LineUp(1)
+		m.scrollOutput(-1)
 		return m, nil
 
 	case "down":
-		m.output.LineDown(1)
+		m.scrollOutput(1)
 		return m, nil
 
 	case "shift+up":
-		m.output.LineUp(8)
+		m.scrollOutput(-8)
 		return m, nil
 
 	case "shift+down":
-		m.output.LineDown(8)
+		m.scrollOutput(8)
 		return m, nil
 
 	case "shift+left":
@@ -214,11 +312,11 @@ func (m Model) handleNormalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "pgup":
-		m.output.HalfViewUp()
+		m.scrollOutput(-m.output.Height / 2)
 		return m, nil
 
 	case "pgdown":
-		m.output.HalfViewDown()
+		m.scrollOutput(m.output.Height / 2)
 		return m, nil
 
 	case "alt+left", "alt+b", "ctrl+left":
@@ -231,25 +329,21 @@ func (m Model) handleNormalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "alt+backspace", "ctrl+backspace", "ctrl+w":
 		if m.deletePrevWord() {
-			m.acContext = m.autocomplete.ParseContext(m.filter.Value())
+			m.refreshContext()
 			return m, tea.Batch(m.queueExecute(), m.maybeFetchKeys())
 		}
 		return m, nil
 
 	case "alt+delete", "alt+d", "ctrl+delete":
 		if m.deleteNextWord() {
-			m.acContext = m.autocomplete.ParseContext(m.filter.Value())
+			m.refreshContext()
 			return m, tea.Batch(m.queueExecute(), m.maybeFetchKeys())
 		}
 		return m, nil
 
 	default:
 		// Text input
-		var cmd tea.Cmd
-		m.filter, cmd = m.filter.Update(msg)
-		// Update autocomplete context so Available keys panel stays in sync
-		m.acContext = m.autocomplete.ParseContext(m.filter.Value())
-		return m, tea.Batch(cmd, m.queueExecute(), m.maybeFetchKeys())
+		return m, m.updateFilterInput(msg)
 	}
 }
 
@@ -275,13 +369,12 @@ func (m Model) handleAutocompleteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		if len(m.suggestions) > 0 {
 			selected := m.suggestions[m.selectedIdx]
-			newFilter := m.autocomplete.Apply(m.filter.Value(), m.acContext, selected)
-			m.filter.SetValue(newFilter)
-			m.filter.SetCursor(len(newFilter))
+			newFilter := m.autocomplete.Apply(m.filterBeforeCursor(), m.acContext, selected)
+			m.replaceBeforeCursor(newFilter)
 		}
 		m.mode = ModeNormal
 		m.suggestions = nil
-		m.acContext = m.autocomplete.ParseContext(m.filter.Value())
+		m.refreshContext()
 		return m, tea.Batch(m.executeNow(), m.maybeFetchKeys())
 
 	default:
@@ -313,11 +406,14 @@ func (m Model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "enter":
 		if len(m.historyItems) > 0 {
-			m.filter.SetValue(m.historyItems[m.historyIdx])
-			m.filter.SetCursor(len(m.historyItems[m.historyIdx]))
+			item := m.historyItems[m.historyIdx]
+			if !m.multiline {
+				item = singleLineFilter(item)
+			}
+			m.setFilter(item, len([]rune(item)))
 		}
 		m.mode = ModeNormal
-		m.acContext = m.autocomplete.ParseContext(m.filter.Value())
+		m.refreshContext()
 		return m, tea.Batch(m.executeNow(), m.maybeFetchKeys())
 
 	default:
@@ -326,6 +422,36 @@ func (m Model) handleHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+func (m Model) handleExportKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	formats := jq.TableFormats
+
+	switch key := msg.String(); key {
+	case "down", "tab":
+		m.exportIdx = (m.exportIdx + 1) % len(formats)
+		return m, nil
+
+	case "up", "shift+tab":
+		m.exportIdx--
+		if m.exportIdx < 0 {
+			m.exportIdx = len(formats) - 1
+		}
+		return m, nil
+
+	case "enter":
+		return m.copyTable(formats[m.exportIdx])
+
+	default:
+		// Each format can also be picked by its first letter
+		for _, f := range formats {
+			if key == string(f[0]) {
+				return m.copyTable(f)
+			}
+		}
+		m.mode = ModeNormal
+		return m, nil
+	}
+}
+
 func (m Model) handleHelpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter", " ":
@@ -336,12 +462,94 @@ func (m Model) handleHelpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+func (m Model) openSavePrompt() (tea.Model, tea.Cmd) {
+	ti := textinput.New()
+	ti.Prompt = "file: "
+	ti.CharLimit = 0
+	ti.Width = 40
+	path := m.filterFile
+	if path == "" {
+		path = "filter.jq"
+	}
+	ti.SetValue(path)
+	ti.CursorEnd()
+	ti.Focus()
+
+	m.saveInput = ti
+	m.saveConfirm = false
+	m.mode = ModeSave
+	return m, textinput.Blink
+}
+
+func (m Model) handleSaveKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	if m.saveConfirm {
+		switch key {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y", "Y":
+			return m.saveFilter(filterFilePath(m.saveInput.Value()))
+		default:
+			// Anything else returns to editing the file name
+			m.saveConfirm = false
+			return m, nil
+		}
+	}
+
+	switch key {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.mode = ModeNormal
+		return m, nil
+
+	case "enter":
+		path := filterFilePath(m.saveInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		if fileExists(path) {
+			m.saveConfirm = true
+			return m, nil
+		}
+		return m.saveFilter(path)
+
+	default:
+		var cmd tea.Cmd
+		m.saveInput, cmd = m.saveInput.Update(msg)
+		return m, cmd
+	}
+}
+
+func (m Model) saveFilter(path string) (tea.Model, tea.Cmd) {
+	m.mode = ModeNormal
+	m.saveConfirm = false
+	if err := writeFilterFile(path, m.programText()); err != nil {
+		m.status = "Save failed: " + err.Error()
+	} else {
+		m.filterFile = path
+		m.status = "Saved filter to " + path
+	}
+	return m, clearStatusAfter(3 * time.Second)
+}
+
+// resultText returns the formatted result, as printed on Enter.
+func (m Model) resultText() string {
+	if m.buffer == nil || m.result.Error != nil {
+		return ""
+	}
+	return m.buffer.Text()
+}
+
 func (m Model) copyOutput() (tea.Model, tea.Cmd) {
-	if m.result.Raw == "" {
+	text := m.resultText()
+	if text == "" {
 		m.status = "Nothing to copy"
 		return m, clearStatusAfter(3 * time.Second)
 	}
-	if err := m.clipboard.Copy(m.result.Raw); err != nil {
+	if err := m.clipboard.Copy(text); err != nil {
 		m.status = "Copy failed: " + err.Error()
 	} else {
 		m.status = "Copied output to clipboard"
@@ -349,21 +557,65 @@ func (m Model) copyOutput() (tea.Model, tea.Cmd) {
 	return m, clearStatusAfter(3 * time.Second)
 }
 
+// copyTable copies the current result as a table in format.
+func (m Model) copyTable(format jq.TableFormat) (tea.Model, tea.Cmd) {
+	m.mode = ModeNormal
+	table, err := jq.ExportTable(m.result.Values, format)
+	switch {
+	case err != nil:
+		m.status = err.Error()
+	case table == "":
+		m.status = "Nothing to copy"
+	default:
+		if err := m.clipboard.Copy(table); err != nil {
+			m.status = "Copy failed: " + err.Error()
+		} else {
+			m.status = "Copied output as " + format.Label()
+		}
+	}
+	return m, clearStatusAfter(3 * time.Second)
+}
+
+func (m Model) toggleOutputOption(key string) (tea.Model, tea.Cmd) {
+	opts := m.jq.OutputOptions()
+	var name string
+	var on bool
+	switch key {
+	case "alt+r":
+		opts.Raw = !opts.Raw
+		name, on = "Raw output", opts.Raw
+	case "alt+c":
+		opts.Compact = !opts.Compact
+		name, on = "Compact output", opts.Compact
+	case "alt+i":
+		opts.Tab = !opts.Tab
+		name, on = "Tab indentation", opts.Tab
+	case "alt+y":
+		opts.YAML = !opts.YAML
+		name, on = "YAML output", opts.YAML
+	}
+	m.jq.SetOutputOptions(opts)
+
+	m.status = name + " off"
+	if on {
+		m.status = name + " on"
+	}
+	return m, tea.Batch(m.executeNow(), clearStatusAfter(3*time.Second))
+}
+
 func (m *Model) moveCursorToPrevWord() {
-	pos := m.filter.Position()
-	nextPos := prevWordStart(m.filter.Value(), pos)
-	m.filter.SetCursor(nextPos)
+	value := m.filterValue()
+	m.setFilter(value, prevWordStart(value, m.filterCursor()))
 }
 
 func (m *Model) moveCursorToNextWord() {
-	pos := m.filter.Position()
-	nextPos := nextWordStart(m.filter.Value(), pos)
-	m.filter.SetCursor(nextPos)
+	value := m.filterValue()
+	m.setFilter(value, nextWordStart(value, m.filterCursor()))
 }
 
 func (m *Model) deletePrevWord() bool {
-	value := m.filter.Value()
-	pos := m.filter.Position()
+	value := m.filterValue()
+	pos := m.filterCursor()
 	start := prevWordStart(value, pos)
 	if start == pos {
 		return false
@@ -371,14 +623,13 @@ func (m *Model) deletePrevWord() bool {
 
 	r := []rune(value)
 	newValue := string(append(r[:start], r[pos:]...))
-	m.filter.SetValue(newValue)
-	m.filter.SetCursor(start)
+	m.setFilter(newValue, start)
 	return true
 }
 
 func (m *Model) deleteNextWord() bool {
-	value := m.filter.Value()
-	pos := m.filter.Position()
+	value := m.filterValue()
+	pos := m.filterCursor()
 	end := nextWordDeleteEnd(value, pos)
 	if end == pos {
 		return false
@@ -386,8 +637,7 @@ func (m *Model) deleteNextWord() bool {
 
 	r := []rune(value)
 	newValue := string(append(r[:pos], r[end:]...))
-	m.filter.SetValue(newValue)
-	m.filter.SetCursor(pos)
+	m.setFilter(newValue, pos)
 	return true
 }
 
@@ -475,7 +725,7 @@ func (m *Model) maybeFetchKeys() tea.Cmd {
 }
 
 func (m Model) copyFilter() (tea.Model, tea.Cmd) {
-	filter := m.filter.Value()
+	filter := m.filterValue()
 	if filter == "" {
 		m.status = "Nothing to copy"
 		return m, clearStatusAfter(3 * time.Second)
@@ -490,7 +740,7 @@ func (m Model) copyFilter() (tea.Model, tea.Cmd) {
 
 func (m Model) contentHeight() int {
 	// Total height minus header (3 lines), footer (3 lines), and borders (2 lines)
-	return m.height - 8
+	return m.height - 8 - m.editorExtraLines()
 }
 
 func (m Model) suggestWidth() int {
@@ -515,6 +765,17 @@ func newViewport(width, height int) viewport.Model {
 	return vp
 }
 
+// scrollOutput moves the output pane delta lines down, or up when negative.
+func (m *Model) scrollOutput(delta int) {
+	m.setOutputOffset(m.output.YOffset + delta)
+}
+
+// setOutputOffset scrolls the output pane to start at body line n, as far
+// as the output allows.
+func (m *Model) setOutputOffset(n int) {
+	m.output.YOffset = max(0, min(n, m.lineCount()-m.output.Height))
+}
+
 func (m *Model) scrollHorizontal(delta int) {
 	m.outputXOffset += delta
 	m.clampOutputXOffset()
@@ -536,11 +797,16 @@ func (m Model) maxHorizontalOffset() int {
 	if outputWidth <= 0 {
 		return 0
 	}
-	maxOffset := m.maxLineWidth - outputWidth
-	if maxOffset < 0 {
+	widest := m.maxLineWidth
+	if m.paged {
+		widest = m.buffer.MaxWidth()
+	}
+	maxOffset := widest - outputWidth
+	if maxOffset <= 0 {
 		return 0
 	}
-	return maxOffset
+	// Once scrolled, the left ellipsis takes a column
+	return maxOffset + 1
 }
 
 func (m Model) showSuggestionPane() bool {
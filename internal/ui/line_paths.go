@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// linePath locates the value an output line belongs to.
+type linePath struct {
+	result int   // Index into the result values
+	path   []any // jq path within that result
+	ok     bool  // False for lines that hold no value, such as a table rule
+}
+
+// refreshLinePaths records the paths of gron statements, which do not
+// follow from the layout of a value. Other lines are resolved when asked
+// for, in linePathAt, so browsing never maps the whole output.
+func (m *Model) refreshLinePaths() {
+	m.gronPaths = nil
+	if m.result.Error != nil || len(m.result.Values) == 0 {
+		return
+	}
+	if m.view == viewGron && !m.ungron || m.paged && m.outputOptions().Gron {
+		m.gronPaths = gronLinePaths(m.result.Values)
+	}
+}
+
+// outputOptions returns the formatting the output pane uses for results.
+func (m Model) outputOptions() jq.OutputOptions {
+	if m.jq == nil {
+		return jq.OutputOptions{}
+	}
+	return m.jq.OutputOptions()
+}
+
+// linePathAt locates the value output line i shows, working from the
+// result values rather than the rendered text.
+func (m Model) linePathAt(i int) (linePath, bool) {
+	values := m.result.Values
+	if m.result.Error != nil || len(values) == 0 || i < 0 || i >= m.lineCount() {
+		return linePath{}, false
+	}
+
+	switch {
+	case m.view == viewSchema:
+		// Rows describe every element of an array at once, not one value
+		return linePath{}, false
+	case m.view == viewTree && !m.viewFallback:
+		tl := m.treeLines[i]
+		return linePath{result: tl.result, path: tl.path, ok: true}, true
+	case m.view == viewTable && !m.viewFallback:
+		if i < m.pinnedLines {
+			return linePath{}, false
+		}
+		row := i - m.pinnedLines
+		if _, isArray := values[0].([]any); len(values) == 1 && isArray {
+			return linePath{path: []any{row}, ok: true}, true
+		}
+		return linePath{result: row, ok: true}, true
+	case m.view == viewGron && m.ungron:
+		path := valueLinePath(m.ungronValue, i, m.ungronOptions())
+		lp := resultPath(values, path)
+		return lp, lp.ok
+	case m.gronPaths != nil:
+		if i >= len(m.gronPaths) || !m.gronPaths[i].ok {
+			return linePath{}, false
+		}
+		return m.gronPaths[i], true
+	case m.paged:
+		result, offset, ok := m.buffer.ValueAt(i)
+		if !ok || result >= len(values) {
+			return linePath{}, false
+		}
+		if offset < 0 {
+			// The --- separator before a YAML document
+			return linePath{result: result, ok: true}, true
+		}
+		return linePath{result: result, path: valueLinePath(values[result], offset, m.outputOptions()), ok: true}, true
+	}
+	return linePath{}, false
+}
+
+// valueLinePath returns the path of the value on line n of v formatted with
+// opts. Indented JSON maps line by line; other layouts resolve to v itself.
+func valueLinePath(v any, n int, opts jq.OutputOptions) []any {
+	if _, isString := v.(string); opts.YAML || opts.Compact || (opts.Raw && isString) {
+		return nil
+	}
+	return jq.PathAtLine(v, n)
+}
+
+// cursorPath returns the location of the value under the output cursor.
+func (m Model) cursorPath() (linePath, bool) {
+	return m.linePathAt(m.cursor)
+}
+
+// breadcrumb describes the value under the cursor for the footer.
+func (m Model) breadcrumb() string {
+	lp, ok := m.cursorPath()
+	if !ok {
+		return ""
+	}
+	crumb := jq.FormatPath(lp.path)
+	if n := len(m.result.Values); n > 1 {
+		crumb += helpStyle.Render(fmt.Sprintf("  (result %d of %d)", lp.result+1, n))
+	}
+	return crumb
+}
+
+func (m Model) copyCursorPath() (tea.Model, tea.Cmd) {
+	lp, ok := m.cursorPath()
+	if !ok {
+		m.status = "No value under the cursor"
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	path := jq.FormatPath(lp.path)
+	if err := m.clipboard.Copy(path); err != nil {
+		m.status = "Copy failed: " + err.Error()
+	} else {
+		m.status = "Copied path " + path
+	}
+	return m, clearStatusAfter(3 * time.Second)
+}
+
+// insertCursorPath extends the filter to select the value under the cursor
+// and returns focus to the filter.
+func (m Model) insertCursorPath() (tea.Model, tea.Cmd) {
+	lp, ok := m.cursorPath()
+	if !ok {
+		m.status = "No value under the cursor"
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	filter := pathFilter(m.filterValue(), jq.FormatPath(lp.path))
+	m.setFilter(filter, len([]rune(filter)))
+	m.mode = ModeNormal
+	m.refreshContext()
+	return m, tea.Batch(m.executeNow(), m.maybeFetchKeys())
+}
+
+// postfixFilterRe matches filters that a path can be appended to directly,
+// such as .items[] or .a."b c"[0].
+var postfixFilterRe = regexp.MustCompile(`^\.?(?:\.?[A-Za-z_][A-Za-z0-9_]*|\.?"(?:[^"\\]|\\.)*"|\[\d*\])*$`)
+
+// pathFilter appends path to filter, chaining it directly onto simple path
+// filters and piping into anything else.
+func pathFilter(filter, path string) string {
+	base := strings.TrimSpace(filter)
+	switch {
+	case base == "" || base == ".":
+		return path
+	case path == ".":
+		return base
+	case postfixFilterRe.MatchString(base):
+		if strings.HasPrefix(path, ".[") {
+			return base + path[1:]
+		}
+		return base + path
+	}
+	return base + " | " + path
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestLinePathsJSON(t *testing.T) {
+	m := Model{
+		mode: ModeBrowse,
+		result: jq.Result{Values: []any{
+			map[string]any{"items": []any{map[string]any{"id": json.Number("7")}}},
+			"second",
+		}},
+	}
+	m.buffer = jq.NewResultBuffer(m.result.Values, jq.OutputOptions{})
+	m.refreshLines()
+
+	want := []string{".", ".items", ".items[0]", ".items[0].id", ".items[0]", ".items", ".", "."}
+	if m.lineCount() != len(want) {
+		t.Fatalf("got %d lines, want %d", m.lineCount(), len(want))
+	}
+	for i, w := range want {
+		lp, ok := m.linePathAt(i)
+		if got := jq.FormatPath(lp.path); !ok || got != w {
+			t.Errorf("line %d (%q) path = %s, want %s", i, m.lineAt(i), got, w)
+		}
+	}
+	if lp, _ := m.linePathAt(7); lp.result != 1 {
+		t.Errorf("last line result = %d, want 1", lp.result)
+	}
+	if _, ok := m.linePathAt(len(want)); ok {
+		t.Error("a line past the end should have no path")
+	}
+
+	m.cursor = 3
+	if lp, _ := m.cursorPath(); jq.FormatPath(lp.path) != ".items[0].id" {
+		t.Errorf("cursor path = %s", jq.FormatPath(lp.path))
+	}
+}
+
+func TestLinePathsTable(t *testing.T) {
+	m := Model{
+		view:   viewTable,
+		mode:   ModeBrowse,
+		result: jq.Result{Values: []any{[]any{map[string]any{"a": "x"}, map[string]any{"a": "y"}}}},
+	}
+	m.refreshLines()
+
+	if m.cursor != m.pinnedLines {
+		t.Fatalf("cursor = %d, want first row %d", m.cursor, m.pinnedLines)
+	}
+	if _, ok := m.linePathAt(0); ok {
+		t.Fatal("header line should have no path")
+	}
+	if lp, _ := m.linePathAt(3); jq.FormatPath(lp.path) != ".[1]" {
+		t.Errorf("second row path = %s, want .[1]", jq.FormatPath(lp.path))
+	}
+}
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		filter, path, want string
+	}{
+		{".", ".items[0].name", ".items[0].name"},
+		{"", ".a", ".a"},
+		{".items", ".[3].name", ".items[3].name"},
+		{".items[]", ".name", ".items[].name"},
+		{`."a b"[0]`, ".c", `."a b"[0].c`},
+		{".a", ".", ".a"},
+		{"map(.x)", ".[0]", "map(.x) | .[0]"},
+		{".a | .b", ".c", ".a | .b | .c"},
+	}
+	for _, tt := range tests {
+		if got := pathFilter(tt.filter, tt.path); got != tt.want {
+			t.Errorf("pathFilter(%q, %q) = %q, want %q", tt.filter, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// loadRefresh is how often the progress bar is redrawn while the input is
+// read.
+const loadRefresh = 100 * time.Millisecond
+
+// inputLoadedMsg reports that the input has been read, or why it could not
+// be.
+type inputLoadedMsg struct {
+	err error
+}
+
+type loadTickMsg struct{}
+
+// waitForInput delivers inputLoadedMsg once the input has been read.
+func (m Model) waitForInput() tea.Cmd {
+	svc := m.jq
+	return func() tea.Msg {
+		return inputLoadedMsg{err: svc.WaitLoaded()}
+	}
+}
+
+func loadTick() tea.Cmd {
+	return tea.Tick(loadRefresh, func(time.Time) tea.Msg {
+		return loadTickMsg{}
+	})
+}
+
+// applyInputLoaded leaves the loading screen. The query typed meanwhile is
+// already waiting for the input and shows up on its own.
+func (m Model) applyInputLoaded(msg inputLoadedMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		// main reports the error once the TUI has exited
+		return m, tea.Quit
+	}
+	if m.validator != nil {
+		return m, m.validateInput()
+	}
+	return m, nil
+}
+
+// loadingLines shows how much of the input has been read in place of the
+// output.
+func (m Model) loadingLines(width int) []string {
+	read, total := m.jq.LoadProgress()
+	lines := []string{"", "  Loading " + m.filename}
+	if total > 0 {
+		done := min(float64(read)/float64(total), 1)
+		barWidth := max(10, min(40, width-30))
+		filled := int(done * float64(barWidth))
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+		lines = append(lines, fmt.Sprintf("  %s %3.0f%%  %s of %s", bar, done*100, formatSize(read), formatSize(total)))
+	} else {
+		lines = append(lines, "  "+formatSize(read)+" read")
+	}
+	return append(lines, "", "  Type a filter now; it runs once the input is loaded.")
+}
+
+// formatSize renders a byte count in binary units.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, prefix := float64(n)/unit, 0
+	for size >= unit && prefix < 2 {
+		size /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", size, "KMG"[prefix])
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/history"
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestTypingWhileInputLoads(t *testing.T) {
+	svc := jq.NewLoadingService(jq.Config{})
+	hist, err := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{Filename: "big.json"})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m = updated.(Model)
+	if !m.loading {
+		t.Fatal("model should wait for the input")
+	}
+
+	// Half the input has arrived
+	input := `{"items": [{"name": "a"}, {"name":"b"}]}` // 40 bytes
+	r, w := io.Pipe()
+	loaded := make(chan error, 1)
+	go func() { loaded <- svc.Load(r, int64(len(input))) }()
+	if _, err := io.WriteString(w, input[:len(input)/2]); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if read, _ := svc.LoadProgress(); read == int64(len(input)/2) || time.Now().After(deadline) {
+			break
+		}
+	}
+	content := m.renderContent()
+	for _, want := range []string{"Loading big.json", "50%", "Type a filter"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("loading screen lacks %q:\n%s", want, content)
+		}
+	}
+	if header := m.renderHeader(); !strings.Contains(header, "Loading input") {
+		t.Fatalf("header = %q", header)
+	}
+
+	// The filter typed meanwhile runs once the input is complete
+	m.setFilter(".items[].name", len(".items[].name"))
+	run := m.executeNow()
+	go func() {
+		_, _ = io.WriteString(w, input[len(input)/2:])
+		w.Close()
+	}()
+	if err := <-loaded; err != nil {
+		t.Fatal(err)
+	}
+	updated, _ = m.Update(m.waitForInput()())
+	m = updated.(Model)
+	msg := run()
+	for {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		if cmd == nil {
+			break
+		}
+		msg = cmd()
+	}
+	if m.loading || m.lineCount() != 2 || m.lineAt(1) != `"b"` {
+		t.Fatalf("loading = %v, output = %q", m.loading, m.lineRange(0, m.lineCount()))
+	}
+}
+
+func TestSlowLoadDoesNotTimeOut(t *testing.T) {
+	svc := jq.NewLoadingService(jq.Config{})
+	hist, err := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{Filter: ".a"})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m = updated.(Model)
+	m.queryTimeout = 50 * time.Millisecond
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = svc.Load(strings.NewReader(`{"a":1}`), 7)
+	}()
+
+	msg := m.executeNow()()
+	for {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		if cmd == nil {
+			break
+		}
+		msg = cmd()
+	}
+	if m.result.Error != nil || m.lineAt(0) != "1" {
+		t.Fatalf("error = %v, output = %q", m.result.Error, m.lineAt(0))
+	}
+}
+
+func TestInputLoadFailureQuits(t *testing.T) {
+	svc := jq.NewLoadingService(jq.Config{})
+	m := Model{jq: svc, loading: true}
+	go func() { _ = svc.Load(strings.NewReader(`{"a":`), 5) }()
+
+	updated, cmd := m.Update(m.waitForInput()())
+	if updated.(Model).loading || cmd == nil {
+		t.Fatal("a failed load should leave the loading screen")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("a failed load should quit so the error can be reported")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:       "512 B",
+		1536:      "1.5 KB",
+		100 << 20: "100.0 MB",
+		3 << 30:   "3.0 GB",
+	} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
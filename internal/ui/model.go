@@ -2,8 +2,13 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -23,10 +28,20 @@ const (
 	ModeAutocomplete
 	ModeHistory
 	ModeHelp
+	ModeSave
+	ModeExport
+	ModeBrowse     // Keys move the output cursor instead of editing the filter
+	ModeSearch     // Typing a search over the output
+	ModeFind       // Looking up the paths where a value occurs
+	ModeViolations // Listing JSON Schema violations
 )
 
 const queryDebounce = 30 * time.Millisecond
 
+// resultRefresh is how often output is redrawn while a query is producing
+// it, and how long the first screen waits to fill up.
+const resultRefresh = 100 * time.Millisecond
+
 // Model is the Bubble Tea model
 type Model struct {
 	// Services
@@ -36,13 +51,32 @@ type Model struct {
 	clipboard    *clipboard.Service
 
 	// Input/Output
-	filter textinput.Model
-	output viewport.Model
-	result jq.Result
-	lines  []string
+	filter     textinput.Model
+	editor     textarea.Model // Multi-line filter editor, toggled with alt+m
+	multiline  bool           // Editing in the multi-line editor instead of filter
+	joinedFrom string         // Multi-line program the one-line filter was joined from
+	output     viewport.Model
+	result     jq.Result
+	buffer     *jq.ResultBuffer // Outputs of the latest query, filled as it runs
+	paged      bool             // Lines are read from buffer instead of lines
+	lines      []string
 	// Output geometry state
 	outputXOffset int
 	maxLineWidth  int
+	view          outputView
+	viewFallback  bool // The view cannot show the result, so lines are JSON
+	pinnedLines   int  // Leading lines kept in place while scrolling (table header)
+	tree          *treeState
+	treeLines     []treeLine
+	cursor        int        // Output line under the cursor in browse mode
+	gronPaths     []linePath // Path of each gron statement line, set while browsing
+
+	// Gron view state
+	ungron      bool           // Show the JSON the statements rebuild
+	ungronRe    *regexp.Regexp // Statements kept by ungron; nil keeps all
+	ungronKept  int            // Statements ungron rebuilt from, of gronTotal
+	gronTotal   int
+	ungronValue any
 
 	// Autocomplete state
 	suggestions   []string
@@ -51,22 +85,71 @@ type Model struct {
 	keysPath      string
 	keysInFlight  string
 	availableKeys []string
+	variables     []variablePreview // User-defined variables listed beside the keys
+	functionNames []string          // Functions matching the name typed, from refreshFunctions
+	functionsFor  string            // Filter text functionNames were listed for
 
 	// History state
 	historyItems []string
 	historyIdx   int
 
+	// Export menu state
+	exportIdx int
+
+	// Output search state
+	searchInput    textinput.Model
+	searchReturn   Mode // Mode to go back to when the prompt closes
+	searchText     string
+	searchRe       *regexp.Regexp
+	searchMatches  []searchMatch
+	searchIdx      int
+	searchSeq      int
+	searchScanning bool // Paged output is still being searched
+	searchFollow   bool // Select the first match from searchFrom when found
+	searchFrom     int
+
+	// Find value state
+	findInput   textinput.Model
+	findQuery   string // Query the matches are for
+	findMatches []jq.PathMatch
+	findLabels  []string // Listed text of the first maxFindMatches matches
+	findIdx     int
+	findSeq     int
+	findRunning bool
+
+	// Schema validation state
+	validator        *jq.Validator
+	schemaPath       string
+	validateResults  bool
+	inputValidated   bool
+	inputViolations  []jq.Violation
+	resultViolations []jq.Violation
+	resultCheckSeq   int // Query whose results are being validated; 0 when none
+	violationIdx     int
+
+	// Save prompt state
+	saveInput   textinput.Model
+	saveConfirm bool   // Waiting for overwrite confirmation
+	filterFile  string // Last .jq file the filter was loaded from or saved to
+
 	// Query execution state
 	querySeq       int
 	activeQuerySeq int
 	queryCancel    context.CancelFunc
 	queryRunning   bool
+	queryTimeout   time.Duration // Evaluation is stopped after this long; 0 never
 
 	// Render cache
 	colorCache *lineColorCache
 	telemetry  *latencyTelemetry
 
+	// Result selected with Enter, printed by the caller after exit
+	emitted    bool
+	emitFilter string
+	emitBuffer *jq.ResultBuffer // Complete output to print; nil reruns emitFilter
+
 	// UI state
+	loading     bool // The input is still being read
 	mode        Mode
 	filename    string
 	filepath    string
@@ -79,9 +162,18 @@ type Model struct {
 
 // Config holds initialization options
 type Config struct {
-	Filename  string
-	Filepath  string
-	Telemetry bool
+	Filename   string
+	Filepath   string
+	Filter     string // Initial filter; defaults to "."
+	FilterFile string // .jq file the initial filter was read from
+	Telemetry  bool
+	Timeout    time.Duration // Longest a query may run; 0 for no limit
+
+	// Schema validates the input, and with ValidateResults each result, when
+	// set. SchemaPath names it in the violations pane.
+	Schema          *jq.Validator
+	SchemaPath      string
+	ValidateResults bool
 }
 
 // NewModel creates a new UI model
@@ -95,19 +187,36 @@ func NewModel(
 	ti := textinput.New()
 	ti.Placeholder = "."
 	ti.Focus()
-	ti.CharLimit = 500
+	ti.CharLimit = 0 // Unlimited; checked-in jq programs can be long
 	ti.Width = 50
-	ti.SetValue(".")
+	if cfg.Filter != "" {
+		ti.SetValue(singleLineFilter(cfg.Filter))
+	} else {
+		ti.SetValue(".")
+	}
 	initialCtx := acSvc.ParseContext(ti.Value())
 
-	return Model{
+	// Programs spanning several lines open in the editor untouched
+	ta := newEditor()
+	multiline := strings.Contains(strings.TrimSpace(cfg.Filter), "\n")
+	if multiline {
+		ti.Blur()
+		ta.SetValue(strings.TrimRight(cfg.Filter, "\n"))
+		ta.Focus()
+		initialCtx = acSvc.ParseContext(ta.Value())
+	}
+
+	m := Model{
 		jq:           jqSvc,
 		autocomplete: acSvc,
 		history:      hist,
 		clipboard:    clip,
 		filter:       ti,
+		editor:       ta,
+		multiline:    multiline,
 		filename:     cfg.Filename,
 		filepath:     cfg.Filepath,
+		filterFile:   cfg.FilterFile,
 		mode:         ModeNormal,
 		acContext:    initialCtx,
 		keysInFlight: initialCtx.Path,
@@ -116,22 +225,42 @@ func NewModel(
 		lines:        []string{""},
 		maxLineWidth: 0,
 		telemetry:    newLatencyTelemetry(cfg.Telemetry),
+		queryTimeout: cfg.Timeout,
+		loading:      !jqSvc.Loaded(),
+		variables:    variablePreviews(jqSvc.Variables()),
+
+		validator:       cfg.Schema,
+		schemaPath:      cfg.SchemaPath,
+		validateResults: cfg.ValidateResults,
 	}
+	m.refreshFunctions(m.filterBeforeCursor())
+	return m
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		textinput.Blink,
+		textarea.Blink,
 		func() tea.Msg { return executeQueryMsg{seq: m.querySeq} },
 		m.fetchKeys(m.currentPath()),
-	)
+	}
+	switch {
+	case m.loading:
+		cmds = append(cmds, m.waitForInput(), loadTick())
+	case m.validator != nil:
+		cmds = append(cmds, m.validateInput())
+	}
+	return tea.Batch(cmds...)
 }
 
 // Message types
+
+// resultMsg reports a query's output: first once a screenful exists, then
+// every resultRefresh until evaluation finishes.
 type resultMsg struct {
 	seq    int
-	result jq.Result
+	buffer *jq.ResultBuffer
 }
 
 type executeQueryMsg struct {
@@ -157,6 +286,7 @@ func (m Model) fetchKeys(path string) tea.Cmd {
 		path = "."
 	}
 	return func() tea.Msg {
+		_ = m.jq.WaitLoaded() // KeysAt reports a failed load
 		keys, err := m.jq.KeysAt(path)
 		return keysMsg{path: path, keys: keys, err: err}
 	}
@@ -187,13 +317,36 @@ func (m *Model) startExecute(seq int) tea.Cmd {
 	m.queryRunning = true
 	m.telemetry.OnDispatch(seq)
 
-	filter := m.filter.Value()
+	filter := m.filterValue()
 	ctx, cancel := context.WithCancel(context.Background())
 	m.queryCancel = cancel
+	timeout := m.queryTimeout
 
+	screen := m.contentHeight()
 	return func() tea.Msg {
-		result := m.jq.ExecuteWithContext(ctx, filter)
-		return resultMsg{seq: seq, result: result}
+		buffer := m.jq.StreamWithTimeout(ctx, filter, timeout)
+		buffer.WaitLines(screen, resultRefresh)
+		return resultMsg{seq: seq, buffer: buffer}
+	}
+}
+
+// timeoutError reports a query stopped for running longer than the timeout.
+type timeoutError struct {
+	after time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.after)
+}
+
+// waitForResults delivers the next update of a query that is still running.
+func waitForResults(seq int, buffer *jq.ResultBuffer) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-buffer.Done():
+		case <-time.After(resultRefresh):
+		}
+		return resultMsg{seq: seq, buffer: buffer}
 	}
 }
 
@@ -216,6 +369,19 @@ func (m Model) TelemetrySummary() (string, bool) {
 	return m.telemetry.Summary()
 }
 
+// WriteOutput writes the result chosen with Enter to w, reporting false if
+// none was. Output that was cut short by the display limits, or was still
+// being produced, is evaluated again in full.
+func (m Model) WriteOutput(w io.Writer) (bool, error) {
+	if !m.emitted {
+		return false, nil
+	}
+	if m.emitBuffer != nil {
+		return true, m.emitBuffer.Print(w)
+	}
+	return true, m.jq.WriteResults(context.Background(), m.emitFilter, w)
+}
+
 // View renders the UI
 func (m Model) View() string {
 	if !m.ready {
@@ -0,0 +1,248 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// outputView selects how results are drawn in the output pane
+type outputView int
+
+const (
+	viewJSON outputView = iota
+	viewTable
+	viewTree
+	viewGron
+	viewSchema
+)
+
+func (v outputView) String() string {
+	switch v {
+	case viewTable:
+		return "table"
+	case viewTree:
+		return "tree"
+	case viewGron:
+		return "gron"
+	case viewSchema:
+		return "schema"
+	}
+	return "json"
+}
+
+// applyResults shows a query's output as it arrives. While evaluation runs
+// the formatted output grows in place; other views, search matches and
+// schema checks catch up when it finishes.
+func (m Model) applyResults(msg resultMsg) (tea.Model, tea.Cmd) {
+	buffer := msg.buffer
+	finished := buffer.Finished()
+	active := msg.seq == m.activeQuerySeq
+	if active && buffer != m.buffer && !m.loading {
+		m.telemetry.OnFrame(msg.seq)
+	}
+	if finished {
+		m.telemetry.OnResult(msg.seq, buffer.Err(), active)
+	}
+	if !active {
+		if !finished {
+			// Follow a superseded query until cancellation stops it
+			return m, waitForResults(msg.seq, buffer)
+		}
+		return m, nil
+	}
+	if finished {
+		m.queryRunning = false
+		m.queryCancel = nil
+	}
+	if errors.Is(buffer.Err(), context.Canceled) {
+		return m, nil
+	}
+
+	first := buffer != m.buffer
+	m.buffer = buffer
+	m.result = jq.Result{Values: buffer.Values(), Error: buffer.Err()}
+	if errors.Is(m.result.Error, context.DeadlineExceeded) {
+		m.result.Error = timeoutError{after: m.queryTimeout}
+	}
+	if m.result.Error != nil {
+		m.result.Values = nil
+	}
+	var scan tea.Cmd
+	if first || finished {
+		m.tree = nil // Folds belong to the previous result
+		scan = m.refreshLines()
+	}
+	if !finished {
+		return m, tea.Batch(scan, waitForResults(msg.seq, buffer))
+	}
+
+	m.resultViolations = nil
+	m.resultCheckSeq = 0
+	if m.validator != nil && m.validateResults && m.result.Error == nil {
+		m.resultCheckSeq = msg.seq
+		return m, tea.Batch(scan, m.validateResultValues(msg.seq))
+	}
+	return m, scan
+}
+
+// refreshLines rebuilds the output lines from the current result for the
+// selected view. Views that cannot show the result fall back to the
+// formatted text. The returned command searches paged output.
+func (m *Model) refreshLines() tea.Cmd {
+	m.pinnedLines = 0
+	m.viewFallback = false
+	m.treeLines = nil
+	m.paged = false
+
+	switch {
+	case m.result.Error != nil:
+		m.lines = strings.Split(m.result.Error.Error(), "\n")
+	case m.view == viewTable:
+		if lines, ok := tableLines(m.result.Values); ok {
+			m.lines = lines
+			m.pinnedLines = tableHeaderLines
+		} else {
+			m.viewFallback = true
+			m.showBuffer()
+		}
+	case m.view == viewTree && len(m.result.Values) > 0:
+		if m.tree == nil {
+			m.tree = newTreeState()
+			m.tree.autoFold(m.result.Values)
+		}
+		m.treeLines = m.tree.lines(m.result.Values)
+		m.lines = make([]string, len(m.treeLines))
+		for i, tl := range m.treeLines {
+			m.lines[i] = tl.text
+		}
+	case m.view == viewSchema && len(m.result.Values) > 0:
+		m.lines = schemaLines(jq.InferSchema(m.result.Values, jq.DefaultSchemaOptions))
+		m.pinnedLines = tableHeaderLines
+	case m.view == viewGron && len(m.result.Values) > 0:
+		m.lines = m.gronLines()
+	default:
+		m.showBuffer()
+	}
+
+	scan := m.refreshSearch()
+	m.maxLineWidth = maxDisplayLineWidth(m.lines)
+	if m.paged {
+		// Lay out the first screen so its width is known
+		m.buffer.Lines(m.output.YOffset, m.output.YOffset+m.contentHeight())
+	}
+	m.clampOutputXOffset()
+	m.setOutputOffset(m.output.YOffset)
+	if m.mode == ModeBrowse {
+		m.refreshLinePaths()
+		m.clampCursor()
+	}
+	return scan
+}
+
+// showBuffer shows the formatted result, read from the buffer a page at a
+// time as it scrolls into view.
+func (m *Model) showBuffer() {
+	m.lines = nil
+	m.paged = m.buffer != nil
+	if !m.paged {
+		m.lines = []string{""}
+	}
+}
+
+// lineCount returns the number of output lines, including pinned ones.
+func (m Model) lineCount() int {
+	if m.paged {
+		return m.buffer.Len()
+	}
+	return len(m.lines)
+}
+
+// lineRange returns output lines start up to end.
+func (m Model) lineRange(start, end int) []string {
+	if m.paged {
+		return m.buffer.Lines(start, end)
+	}
+	start, end = max(start, 0), min(end, len(m.lines))
+	if start >= end {
+		return nil
+	}
+	return m.lines[start:end]
+}
+
+// lineAt returns output line i, or "" past the end.
+func (m Model) lineAt(i int) string {
+	if line := m.lineRange(i, i+1); len(line) == 1 {
+		return line[0]
+	}
+	return ""
+}
+
+// toggleView switches the output pane to v, or back to JSON if v is showing.
+func (m Model) toggleView(v outputView) (tea.Model, tea.Cmd) {
+	if m.view == v {
+		v = viewJSON
+	}
+	m.view = v
+	m.ungron = false
+	m.outputXOffset = 0
+	m.cursor = 0
+	scan := m.refreshLines()
+	m.output.YOffset = 0
+
+	// The tree is navigated with the output cursor
+	if v == viewTree && !m.viewFallback && m.mode != ModeBrowse {
+		m.mode = ModeBrowse
+		m.suggestions = nil
+		m.refreshLinePaths()
+		m.clampCursor()
+	}
+
+	m.status = "View: " + v.String()
+	if m.viewFallback {
+		m.status += " (not available for this result; showing JSON)"
+	}
+	return m, tea.Batch(scan, clearStatusAfter(3*time.Second))
+}
+
+// visibleLines returns the lines drawn in the output pane and the index of
+// the first body line.
+func (m Model) visibleLines(height int) ([]string, int) {
+	height = max(height, 0) // The terminal may be too small for any output
+	if m.paged {
+		return m.buffer.Lines(m.output.YOffset, m.output.YOffset+height), m.output.YOffset
+	}
+	lines := m.lines
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	return m.visibleLineRange(lines, height)
+}
+
+// visibleLineRange returns the lines drawn in the output pane: any pinned
+// header lines followed by the scrolled body, and the index of the first
+// body line.
+func (m Model) visibleLineRange(lines []string, height int) ([]string, int) {
+	pinned := m.pinnedLines
+	if pinned > len(lines) || pinned > height {
+		pinned = 0
+	}
+
+	start := pinned + m.output.YOffset
+	end := start + height - pinned
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		start = end
+	}
+
+	visible := make([]string, 0, end-start+pinned)
+	visible = append(visible, lines[:pinned]...)
+	return append(visible, lines[start:end]...), start
+}
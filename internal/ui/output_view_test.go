@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/history"
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func newStreamModel(t *testing.T, filter string) Model {
+	t.Helper()
+	return newLimitedModel(t, filter, jq.Limits{})
+}
+
+func newLimitedModel(t *testing.T, filter string, limits jq.Limits) Model {
+	t.Helper()
+	svc, err := jq.NewServiceWithConfig([]byte(`{"n":3}`), jq.Config{Limits: limits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist, err := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{Filter: filter})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	return updated.(Model)
+}
+
+func TestResultsShowBeforeEvaluationFinishes(t *testing.T) {
+	m := newStreamModel(t, "range(1e12)")
+
+	updated, cmd := m.Update(m.executeNow()())
+	m = updated.(Model)
+	if !m.queryRunning || cmd == nil {
+		t.Fatal("an endless filter should still be running after the first screen")
+	}
+	if !m.paged || m.lineCount() < m.contentHeight() {
+		t.Fatalf("got %d lines, want at least a screen (%d)", m.lineCount(), m.contentHeight())
+	}
+	last := m.contentHeight() - 1
+	if got := m.lineAt(last); got != strconv.Itoa(last) {
+		t.Fatalf("last visible line = %q, want %d", got, last)
+	}
+
+	// Updates keep arriving until the query is cancelled
+	updated, cmd = m.Update(cmd())
+	m = updated.(Model)
+	if cmd == nil {
+		t.Fatal("no further update scheduled while running")
+	}
+	m.queryCancel()
+	updated, _ = m.Update(cmd())
+	m = updated.(Model)
+	if m.queryRunning {
+		t.Fatal("cancelled query still marked as running")
+	}
+}
+
+func TestFinishedResultsRefreshViews(t *testing.T) {
+	m := newStreamModel(t, "[{n}, {n}]")
+	m.view = viewTable
+
+	updated, cmd := m.Update(m.executeNow()())
+	m = updated.(Model)
+	if m.queryRunning || cmd != nil {
+		t.Fatal("a short query should finish with the first update")
+	}
+	if m.viewFallback || m.paged {
+		t.Fatal("table view should show the finished result as a table")
+	}
+	if got := m.resultText(); got != "[\n  {\n    \"n\": 3\n  },\n  {\n    \"n\": 3\n  }\n]" {
+		t.Fatalf("resultText = %q", got)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	m := newLimitedModel(t, "last(range(1e12))", jq.Limits{})
+	m.queryTimeout = 50 * time.Millisecond
+
+	msg := m.executeNow()()
+	for {
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		if cmd == nil {
+			break
+		}
+		msg = cmd()
+	}
+	if m.queryRunning {
+		t.Fatal("query still running after the timeout")
+	}
+	if header := m.renderHeader(); !strings.Contains(header, "timed out after 50ms") || strings.Contains(header, "Error:") {
+		t.Fatalf("header = %q", header)
+	}
+	if m.lineAt(0) != "timed out after 50ms" {
+		t.Fatalf("output = %q", m.lineAt(0))
+	}
+}
+
+func TestTruncatedResults(t *testing.T) {
+	m := newLimitedModel(t, "range(10)", jq.Limits{Results: 3})
+
+	updated, _ := m.Update(m.executeNow()())
+	m = updated.(Model)
+	if m.lineCount() != 3 {
+		t.Fatalf("showing %d lines, want 3", m.lineCount())
+	}
+	if header := m.renderHeader(); !strings.Contains(header, "showing first 3 results (truncated)") {
+		t.Fatalf("header = %q", header)
+	}
+
+	// Enter prints the whole result
+	updated, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	var out strings.Builder
+	if ok, err := m.WriteOutput(&out); !ok || err != nil {
+		t.Fatalf("WriteOutput = %v, %v", ok, err)
+	}
+	if want := "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n"; out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
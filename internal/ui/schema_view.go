@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// maxSchemaPathWidth lets deep paths use more of the pane than table cells.
+const maxSchemaPathWidth = 60
+
+// schemaLines lays out an inferred schema as a table with one row per path:
+// the types seen there, how often it was present and example values.
+func schemaLines(s *jq.Schema) []string {
+	var cells [][]string
+	var walk func(path string, s *jq.Schema, present string)
+	walk = func(path string, s *jq.Schema, present string) {
+		if s.Sampled {
+			present += " (sampled)"
+		}
+		examples := make([]string, len(s.Examples))
+		for i, e := range s.Examples {
+			examples[i] = tableCellEscaper.Replace(jq.FormatValue(e, jq.OutputOptions{Compact: true}))
+		}
+		label := path
+		if !strings.HasPrefix(label, ".") {
+			label = "." + label // The root, or elements of a root array
+		}
+		cells = append(cells, []string{label, strings.Join(s.TypeNames(), " | "), present, strings.Join(examples, ", ")})
+
+		objects := s.Types["object"]
+		for _, k := range s.PropertyNames() {
+			prop := s.Properties[k]
+			walk(path+jq.FormatPath([]any{k}), prop, fmt.Sprintf("%d/%d", prop.Count, objects))
+		}
+		if s.Items != nil && s.Items.Count > 0 {
+			walk(path+"[]", s.Items, fmt.Sprintf("%d", s.Items.Count))
+		}
+	}
+	walk("", s, fmt.Sprintf("%d", s.Count))
+	return layoutTable([]string{"path", "type", "present", "examples"}, cells, map[int]int{0: maxSchemaPathWidth, 3: 2 * maxTableColumnWidth})
+}
+
+// copySchema copies the schema inferred from the current result as a JSON
+// Schema document.
+func (m Model) copySchema() (tea.Model, tea.Cmd) {
+	if m.result.Error != nil || len(m.result.Values) == 0 {
+		m.status = "Nothing to copy"
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	schema := jq.InferSchema(m.result.Values, jq.DefaultSchemaOptions).JSONSchema()
+	if err := m.clipboard.Copy(jq.FormatValue(schema, jq.OutputOptions{})); err != nil {
+		m.status = "Copy failed: " + err.Error()
+	} else {
+		m.status = "Copied inferred JSON Schema"
+	}
+	return m, clearStatusAfter(3 * time.Second)
+}
@@ -0,0 +1,27 @@
+package ui
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestSchemaLines(t *testing.T) {
+	values := []any{[]any{
+		map[string]any{"id": json.Number("1"), "name": "a"},
+		map[string]any{"id": json.Number("2")},
+	}}
+	got := schemaLines(jq.InferSchema(values, jq.DefaultSchemaOptions))
+	want := []string{
+		"path     │ type    │ present │ examples",
+		"─────────┼─────────┼─────────┼─────────",
+		".        │ array   │ 1       │",
+		".[]      │ object  │ 2       │",
+		".[].id   │ integer │ 2/2     │ 1, 2",
+		`.[].name │ string  │ 1/2     │ "a"`,
+	}
+	if !equalStringSlices(got, want) {
+		t.Fatalf("schemaLines =\n%q\nwant\n%q", got, want)
+	}
+}
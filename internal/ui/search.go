@@ -0,0 +1,419 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// maxSearchMatches bounds the work done per keystroke on huge outputs.
+const maxSearchMatches = 10000
+
+// Search highlights are SGR codes so they layer over the JSON colors.
+const (
+	ansiMatch        = "\x1b[7m"     // Reverse video
+	ansiCurrentMatch = "\x1b[30;43m" // Black on yellow
+)
+
+// searchMatch is a match in the output, as rune offsets within a line.
+type searchMatch struct {
+	line, start, end int
+}
+
+// compileSearch turns what was typed into a pattern. Input that is not a
+// valid regex is matched literally, and all-lowercase input ignores case.
+func compileSearch(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	flags := ""
+	if strings.IndexFunc(pattern, unicode.IsUpper) < 0 {
+		flags = "(?i)"
+	}
+	if re, err := regexp.Compile(flags + pattern); err == nil {
+		return re
+	}
+	return regexp.MustCompile(flags + regexp.QuoteMeta(pattern))
+}
+
+// findMatches lists non-empty matches of re in lines, skipping the pinned
+// header.
+func findMatches(re *regexp.Regexp, lines []string, pinned int) []searchMatch {
+	if re == nil {
+		return nil
+	}
+	var matches []searchMatch
+	for i := pinned; i < len(lines); i++ {
+		line := lines[i]
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start := utf8.RuneCountInString(line[:loc[0]])
+			end := start + utf8.RuneCountInString(line[loc[0]:loc[1]])
+			matches = append(matches, searchMatch{line: i, start: start, end: end})
+			if len(matches) == maxSearchMatches {
+				return matches
+			}
+		}
+	}
+	return matches
+}
+
+// searchChunk is the number of paged output lines laid out per step of a
+// background search.
+const searchChunk = 4096
+
+// searchChunkMsg carries the matches in paged output lines start up to end.
+type searchChunkMsg struct {
+	seq        int
+	start, end int
+	matches    []searchMatch
+}
+
+// scanSearch searches the paged output lines from start in the background.
+// Each chunk asks for the next, so a newer search stops the scan.
+func scanSearch(seq int, buffer *jq.ResultBuffer, re *regexp.Regexp, start int) tea.Cmd {
+	return func() tea.Msg {
+		lines := buffer.Lines(start, start+searchChunk)
+		matches := findMatches(re, lines, 0)
+		for i := range matches {
+			matches[i].line += start
+		}
+		return searchChunkMsg{seq: seq, start: start, end: start + len(lines), matches: matches}
+	}
+}
+
+// startSearch searches the output for the active pattern. In-memory lines
+// are searched at once; paged output is scanned in the background and the
+// returned command starts it.
+func (m *Model) startSearch() tea.Cmd {
+	m.searchSeq++
+	m.searchMatches = nil
+	m.searchScanning = false
+	if m.searchRe == nil {
+		return nil
+	}
+	if !m.paged {
+		m.searchMatches = findMatches(m.searchRe, m.lines, m.pinnedLines)
+		return nil
+	}
+	m.searchScanning = true
+	return scanSearch(m.searchSeq, m.buffer, m.searchRe, 0)
+}
+
+func (m Model) applySearchChunk(msg searchChunkMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.searchSeq {
+		return m, nil
+	}
+	m.searchMatches = append(m.searchMatches, msg.matches...)
+	done := len(m.searchMatches) >= maxSearchMatches || msg.end >= m.buffer.Len()
+	m.searchMatches = m.searchMatches[:min(len(m.searchMatches), maxSearchMatches)]
+
+	// The first match after the search started is selected once it is found
+	if m.searchFollow && len(m.searchMatches) > 0 && (m.selectMatchFrom(m.searchFrom) || done) {
+		m.searchFollow = false
+		m.scrollToMatch()
+	}
+	if !done {
+		return m, scanSearch(msg.seq, m.buffer, m.searchRe, msg.end)
+	}
+	m.searchScanning = false
+	if m.searchIdx >= len(m.searchMatches) {
+		m.searchIdx = 0
+	}
+	return m, nil
+}
+
+// selectMatchFrom selects the first match at or after line top, if any.
+func (m *Model) selectMatchFrom(top int) bool {
+	i := sort.Search(len(m.searchMatches), func(i int) bool {
+		return m.searchMatches[i].line >= top
+	})
+	if i == len(m.searchMatches) {
+		return false
+	}
+	m.searchIdx = i
+	return true
+}
+
+func (m Model) openSearch() (tea.Model, tea.Cmd) {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.CharLimit = 0
+	ti.Width = 40
+	ti.SetValue(m.searchInput.Value())
+	ti.CursorEnd()
+	ti.Focus()
+
+	m.searchInput = ti
+	m.searchReturn = m.mode
+	if m.searchReturn != ModeBrowse {
+		m.searchReturn = ModeNormal
+	}
+	m.mode = ModeSearch
+	m.suggestions = nil
+	return m, textinput.Blink
+}
+
+func (m Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc":
+		m.searchInput.SetValue("")
+		m.setSearch("")
+		m.mode = m.searchReturn
+		return m, nil
+
+	case "enter":
+		m.mode = m.searchReturn
+		if m.searchRe == nil {
+			return m, nil
+		}
+		if len(m.searchMatches) == 0 {
+			if m.searchScanning {
+				return m, nil // The first match is shown when the scan finds it
+			}
+			m.status = "Pattern not found: " + m.searchInput.Value()
+			return m, clearStatusAfter(3 * time.Second)
+		}
+		// n and N step through matches from the output cursor
+		if len(m.result.Values) > 0 {
+			m.mode = ModeBrowse
+			m.refreshLinePaths()
+		}
+		m.scrollToMatch()
+		return m, nil
+
+	default:
+		var cmd, scan tea.Cmd
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		if m.searchInput.Value() != m.searchPattern() {
+			scan = m.setSearch(m.searchInput.Value())
+			m.scrollToMatch()
+		}
+		return m, tea.Batch(cmd, scan)
+	}
+}
+
+// searchPattern is the text of the active search.
+func (m Model) searchPattern() string {
+	if m.searchRe == nil {
+		return ""
+	}
+	return m.searchText
+}
+
+// setSearch starts a search for pattern, selecting the first match at or
+// after the top of the output pane.
+func (m *Model) setSearch(pattern string) tea.Cmd {
+	m.searchText = pattern
+	m.searchRe = compileSearch(pattern)
+	m.searchIdx = 0
+	m.searchFrom = m.pinnedLines + m.output.YOffset
+	if m.searchReturn == ModeBrowse {
+		m.searchFrom = m.cursor
+	}
+	scan := m.startSearch()
+	m.searchFollow = m.searchScanning
+	if !m.searchScanning && !m.selectMatchFrom(m.searchFrom) {
+		m.searchIdx = 0
+	}
+	return scan
+}
+
+// refreshSearch reruns the active search over new output lines.
+func (m *Model) refreshSearch() tea.Cmd {
+	m.searchFollow = false
+	scan := m.startSearch()
+	if !m.searchScanning && m.searchIdx >= len(m.searchMatches) {
+		m.searchIdx = 0
+	}
+	return scan
+}
+
+// stepMatch moves to the next (delta 1) or previous (delta -1) match,
+// wrapping around the output.
+func (m *Model) stepMatch(delta int) {
+	n := len(m.searchMatches)
+	if n == 0 {
+		if m.searchRe != nil && !m.searchScanning {
+			m.status = "Pattern not found: " + m.searchText
+		}
+		return
+	}
+	m.searchIdx = ((m.searchIdx+delta)%n + n) % n
+	m.scrollToMatch()
+}
+
+// scrollToMatch brings the current match into view, horizontally too, and
+// puts the output cursor on it while browsing.
+func (m *Model) scrollToMatch() {
+	if m.searchIdx >= len(m.searchMatches) {
+		return
+	}
+	match := m.searchMatches[m.searchIdx]
+
+	if m.mode == ModeBrowse {
+		m.cursor = match.line
+		m.clampCursor()
+	} else {
+		row := match.line - m.pinnedLines
+		switch {
+		case row < m.output.YOffset:
+			m.setOutputOffset(row)
+		case row >= m.output.YOffset+m.bodyHeight():
+			m.setOutputOffset(row - m.bodyHeight() + 1)
+		}
+	}
+
+	// Clipped lines spend a column on each ellipsis
+	line := m.lineAt(match.line)
+	start, end := displayColumn(line, match.start), displayColumn(line, match.end)
+	width := m.outputContentWidth() - 2
+	if start < m.outputXOffset+1 || end > m.outputXOffset+width {
+		m.outputXOffset = start - width/4
+		if m.outputXOffset < 0 || end <= width {
+			m.outputXOffset = 0
+		}
+		m.clampOutputXOffset()
+	}
+}
+
+// searchLabel reports the match position for the footer.
+func (m Model) searchLabel() string {
+	if m.searchRe == nil {
+		return ""
+	}
+	n := len(m.searchMatches)
+	switch {
+	case n == 0 && m.searchScanning:
+		return "searching…"
+	case n == 0:
+		return "no matches"
+	}
+	total := fmt.Sprint(n)
+	if n == maxSearchMatches || m.searchScanning {
+		total += "+"
+	}
+	return fmt.Sprintf("match %d/%s", m.searchIdx+1, total)
+}
+
+// highlightRange marks visible runes [start, end) of a rendered line.
+type highlightRange struct {
+	start, end int
+	current    bool
+}
+
+// lineHighlights maps the matches on output line idx onto its clipped text,
+// which starts at rune clipStart and may carry ellipses.
+func (m Model) lineHighlights(idx, clipStart int, clipped string, leftCut, rightCut bool) []highlightRange {
+	first := sort.Search(len(m.searchMatches), func(i int) bool {
+		return m.searchMatches[i].line >= idx
+	})
+	if first == len(m.searchMatches) || m.searchMatches[first].line != idx {
+		return nil
+	}
+
+	lo, hi := 0, utf8.RuneCountInString(clipped)
+	shift := -clipStart
+	if leftCut {
+		lo, shift = 1, shift+1
+	}
+	if rightCut {
+		hi--
+	}
+
+	var ranges []highlightRange
+	for i := first; i < len(m.searchMatches) && m.searchMatches[i].line == idx; i++ {
+		start := max(m.searchMatches[i].start+shift, lo)
+		end := min(m.searchMatches[i].end+shift, hi)
+		if start < end {
+			ranges = append(ranges, highlightRange{start: start, end: end, current: i == m.searchIdx})
+		}
+	}
+	return ranges
+}
+
+// highlightMatches wraps ranges of visible runes in s with match colors.
+// s may already hold ANSI color codes; they are kept, and the highlight is
+// restored after any that fall inside a match.
+func highlightMatches(s string, ranges []highlightRange) string {
+	if len(ranges) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	lastColor := "" // Color in effect outside the highlight
+	active := ""    // Highlight code in effect, if inside a match
+	r, pos := 0, 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := i + 1
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e || s[j] == '[') {
+				j++
+			}
+			if j < len(s) {
+				j++
+			}
+			seq := s[i:j]
+			b.WriteString(seq)
+			if seq == ansiReset {
+				lastColor = ""
+			} else {
+				lastColor = seq
+			}
+			if active != "" {
+				b.WriteString(active)
+			}
+			i = j
+			continue
+		}
+
+		if active != "" && pos == ranges[r].end {
+			b.WriteString(ansiReset + lastColor)
+			active = ""
+			r++
+		}
+		if active == "" && r < len(ranges) && pos == ranges[r].start {
+			active = ansiMatch
+			if ranges[r].current {
+				active = ansiCurrentMatch
+			}
+			b.WriteString(active)
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		pos++
+	}
+	if active != "" {
+		b.WriteString(ansiReset + lastColor)
+	}
+	return b.String()
+}
+
+// displayColumn is the terminal column where rune idx of line starts.
+func displayColumn(line string, idx int) int {
+	col := 0
+	for i, r := range []rune(line) {
+		if i == idx {
+			break
+		}
+		col += max(runewidth.RuneWidth(r), 1)
+	}
+	return col
+}
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func TestCompileSearch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		want          bool
+	}{
+		{"name", `"Name": 1`, true},  // Lowercase ignores case
+		{"Name", `"name": 1`, false}, // Upper case is exact
+		{`id\d+`, "id42", true},
+		{"a(b", "xa(b", true}, // Invalid regex matches literally
+	}
+	for _, tt := range tests {
+		if got := compileSearch(tt.pattern).MatchString(tt.text); got != tt.want {
+			t.Errorf("compileSearch(%q) match %q = %v, want %v", tt.pattern, tt.text, got, tt.want)
+		}
+	}
+	if compileSearch("") != nil {
+		t.Error("empty pattern should not search")
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	lines := []string{"id │ name", "é id id", "none"}
+	got := findMatches(compileSearch("id"), lines, 1)
+	want := []searchMatch{{1, 2, 4}, {1, 5, 7}}
+	if len(got) != len(want) {
+		t.Fatalf("matches = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("match %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHighlightMatchesKeepsColors(t *testing.T) {
+	line := `  "name": "web"`
+	colored := colorizeJSON(line)
+	got := highlightMatches(colored, []highlightRange{{start: 3, end: 7, current: true}})
+
+	if plain := ansiRe.ReplaceAllString(got, ""); plain != line {
+		t.Fatalf("visible text = %q, want %q", plain, line)
+	}
+	// The highlight is restored after the key color resets inside the match
+	want := `"` + ansiCyan + ansiCurrentMatch + "name" + ansiReset + ansiCurrentMatch
+	if !strings.Contains(got, want) {
+		t.Fatalf("highlight = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestSearchScrollsToMatch(t *testing.T) {
+	long := strings.Repeat("x", 200) + "needle"
+	m := Model{
+		width:      60,
+		height:     20,
+		colorCache: newLineColorCache(16),
+		result:     jq.Result{Values: []any{"first\n" + long}},
+	}
+	m.buffer = jq.NewResultBuffer(m.result.Values, jq.OutputOptions{Raw: true})
+	m.output = newViewport(m.width, m.contentHeight())
+	m.refreshLines()
+
+	scan := m.setSearch("needle")
+	updated, _ := m.Update(scan())
+	m = updated.(Model)
+	if got := m.searchLabel(); got != "match 1/1" {
+		t.Fatalf("label = %q, want match 1/1", got)
+	}
+	start := displayColumn(long, 200)
+	if m.outputXOffset == 0 || m.outputXOffset > start {
+		t.Fatalf("outputXOffset = %d, want the match at column %d in view", m.outputXOffset, start)
+	}
+
+	view := m.renderContent()
+	if !strings.Contains(view, ansiCurrentMatch+"needle") {
+		t.Fatalf("rendered output does not highlight the match:\n%s", view)
+	}
+}
+
+func TestSearchScansPagedOutputInBackground(t *testing.T) {
+	items := make([]any, 3*searchChunk)
+	for i := range items {
+		items[i] = json.Number("1")
+	}
+	m := Model{
+		width:      60,
+		height:     20,
+		colorCache: newLineColorCache(16),
+		result:     jq.Result{Values: []any{items, "needle"}},
+	}
+	m.buffer = jq.NewResultBuffer(m.result.Values, jq.OutputOptions{})
+	m.output = newViewport(m.width, m.contentHeight())
+	m.refreshLines()
+
+	scan := m.setSearch("needle")
+	if scan == nil || m.searchLabel() != "searching…" {
+		t.Fatalf("label = %q, want the scan to run in the background", m.searchLabel())
+	}
+
+	// A newer search drops the chunks of the old one
+	stale := scan()
+	scan = m.setSearch("needle")
+	updated, next := m.Update(stale)
+	if m = updated.(Model); next != nil || len(m.searchMatches) != 0 {
+		t.Fatalf("stale chunk was applied: %d matches", len(m.searchMatches))
+	}
+
+	chunks := 0
+	for scan != nil {
+		updated, scan = m.Update(scan())
+		m = updated.(Model)
+		chunks++
+	}
+	if chunks < 3 {
+		t.Fatalf("scanned %d chunks, want the output searched a chunk at a time", chunks)
+	}
+	if got := m.searchLabel(); got != "match 1/1" {
+		t.Fatalf("label = %q, want match 1/1", got)
+	}
+	if last := m.buffer.Len() - 1; m.output.YOffset+m.bodyHeight() <= last {
+		t.Fatalf("YOffset = %d, want line %d in view", m.output.YOffset, last)
+	}
+}
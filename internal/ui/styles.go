@@ -30,6 +30,9 @@ var (
 	labelStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("8"))
 
+	cursorStyle = lipgloss.NewStyle().
+			Reverse(true)
+
 	historyOverlayStyle = lipgloss.NewStyle().
 				Border(lipgloss.DoubleBorder()).
 				BorderForeground(lipgloss.Color("12")).
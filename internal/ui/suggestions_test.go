@@ -1,6 +1,12 @@
 package ui
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
 
 func TestFilterKeysByPrefix(t *testing.T) {
 	keys := []string{"meta", "users", "version", "Value"}
@@ -37,3 +43,53 @@ func equalStringSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestFunctionSuggestionsFollowFilter(t *testing.T) {
+	m := newStreamModel(t, ".n | sel")
+	if !slices.Contains(m.functionNames, "select") {
+		t.Fatalf("functionNames = %v", m.functionNames)
+	}
+	if panel := m.renderSuggestions(); !strings.Contains(panel, "select") {
+		t.Fatalf("suggestions panel lacks select:\n%s", panel)
+	}
+
+	m.setFilter(".n | tojs", len(".n | tojs"))
+	m.refreshContext()
+	if !slices.Contains(m.functionNames, "tojson") || slices.Contains(m.functionNames, "select") {
+		t.Fatalf("functionNames = %v", m.functionNames)
+	}
+
+	m.setFilter(".n", 2)
+	m.refreshContext()
+	if m.functionNames != nil {
+		t.Fatalf("functionNames outside a function name = %v", m.functionNames)
+	}
+}
+
+func TestPreviewValue(t *testing.T) {
+	long := strings.Repeat("é", previewRunes+50)
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{v: "short", want: `"short"`},
+		{v: long, want: `"` + strings.Repeat("é", previewRunes) + `"…`},
+		{v: map[string]any{"a": 1, "b": 2}, want: "{…} 2 keys"},
+		{v: []any{1}, want: "[…] 1 item"},
+		{v: []any{}, want: "[]"},
+		{v: nil, want: "null"},
+	}
+	for _, tt := range tests {
+		if got := previewValue(tt.v); got != tt.want {
+			t.Errorf("previewValue(%.20v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+
+	vars := variablePreviews([]jq.Variable{
+		{Name: "$lines", Value: make([]any, 100000)},
+		{Name: "$ARGS", Value: map[string]any{}},
+	})
+	if len(vars) != 1 || vars[0].preview != "[…] 100000 items" {
+		t.Fatalf("variablePreviews = %+v", vars)
+	}
+}
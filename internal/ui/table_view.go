@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+const (
+	maxTableColumnWidth = 40
+	tableColumnSep      = " │ "
+	tableHeaderLines    = 2 // Column names and the rule below them
+)
+
+var tableCellEscaper = strings.NewReplacer("\r\n", "⏎", "\n", "⏎", "\t", " ")
+
+// tableLines lays out values as a text table with a row number column, one
+// row per element and one column per key. Cells wider than
+// maxTableColumnWidth are clipped with an ellipsis. It reports false when
+// the values are not an array or stream of objects or arrays.
+func tableLines(values []any) ([]string, bool) {
+	header, rows, err := jq.Table(values)
+	if err != nil || len(rows) == 0 {
+		return nil, false
+	}
+
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if header == nil {
+		// Rows of arrays are labelled by position
+		header = make([]string, width)
+		for i := range header {
+			header[i] = strconv.Itoa(i)
+		}
+	}
+
+	cols := append([]string{"#"}, header...)
+	cells := make([][]string, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]string, len(cols))
+		cells[r][0] = strconv.Itoa(r)
+		for c, cell := range row {
+			cells[r][c+1] = tableCellEscaper.Replace(cell)
+		}
+	}
+	return layoutTable(cols, cells, nil), true
+}
+
+// layoutTable aligns cells under cols with a rule below the header. Columns
+// are as wide as their widest cell, up to maxTableColumnWidth or the limit
+// given for that column in maxWidths.
+func layoutTable(cols []string, cells [][]string, maxWidths map[int]int) []string {
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		widths[i] = runewidth.StringWidth(c)
+	}
+	for _, row := range cells {
+		for c, cell := range row {
+			if w := runewidth.StringWidth(cell); c < len(widths) && w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+	for i, w := range widths {
+		limit := maxTableColumnWidth
+		if l, ok := maxWidths[i]; ok {
+			limit = l
+		}
+		if w > limit {
+			widths[i] = limit
+		}
+	}
+
+	lines := make([]string, 0, len(cells)+tableHeaderLines)
+	lines = append(lines, tableRow(cols, widths))
+	rule := make([]string, len(widths))
+	for i, w := range widths {
+		rule[i] = strings.Repeat("─", w)
+	}
+	lines = append(lines, strings.Join(rule, "─┼─"))
+	for _, row := range cells {
+		lines = append(lines, tableRow(row, widths))
+	}
+	return lines
+}
+
+func tableRow(cells []string, widths []int) string {
+	parts := make([]string, len(widths))
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		clipped, _, rightCut := clipRawLine(cell, 0, w)
+		clipped = withEllipsis(clipped, w, false, rightCut)
+		if pad := w - runewidth.StringWidth(clipped); pad > 0 {
+			clipped += strings.Repeat(" ", pad)
+		}
+		parts[i] = clipped
+	}
+	return strings.TrimRight(strings.Join(parts, tableColumnSep), " ")
+}
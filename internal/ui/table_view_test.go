@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTableLines(t *testing.T) {
+	values := []any{[]any{
+		map[string]any{"id": json.Number("1"), "name": "Ada"},
+		map[string]any{"id": json.Number("2"), "name": strings.Repeat("x", 50), "note": "a\nb"},
+	}}
+
+	lines, ok := tableLines(values)
+	if !ok {
+		t.Fatal("tableLines() ok = false, want true")
+	}
+	want := []string{
+		"# │ id │ name                                     │ note",
+		"──┼────┼──────────────────────────────────────────┼─────",
+		"0 │ 1  │ Ada                                      │",
+		"1 │ 2  │ " + strings.Repeat("x", maxTableColumnWidth-1) + "… │ a⏎b",
+	}
+	if !equalStringSlices(lines, want) {
+		t.Fatalf("tableLines() =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, ok := tableLines([]any{"a", "b"}); ok {
+		t.Error("tableLines() accepted a stream of strings")
+	}
+	if _, ok := tableLines([]any{[]any{}}); ok {
+		t.Error("tableLines() accepted an empty array")
+	}
+}
+
+func TestVisibleLineRangePinsHeader(t *testing.T) {
+	lines := []string{"h", "-", "r0", "r1", "r2", "r3", "r4"}
+	m := Model{pinnedLines: 2}
+
+	m.output.YOffset = 0
+	if got, _ := m.visibleLineRange(lines, 4); !equalStringSlices(got, []string{"h", "-", "r0", "r1"}) {
+		t.Fatalf("top = %v", got)
+	}
+
+	// The viewport stops at len(lines)-height, which shows the last row
+	m.output.YOffset = 3
+	if got, _ := m.visibleLineRange(lines, 4); !equalStringSlices(got, []string{"h", "-", "r3", "r4"}) {
+		t.Fatalf("bottom = %v", got)
+	}
+
+	m.pinnedLines = 0
+	m.output.YOffset = 5
+	if got, _ := m.visibleLineRange(lines, 4); !equalStringSlices(got, []string{"r3", "r4"}) {
+		t.Fatalf("unpinned = %v", got)
+	}
+}
@@ -20,11 +20,13 @@ type latencyTelemetry struct {
 	droppedDebounce int
 	staleResults    int
 	canceledResults int
+	timedOut        int
 }
 
 type latencySpan struct {
 	queuedAt   time.Time
 	dispatched time.Time
+	framed     bool // The first screen of output has been shown
 }
 
 func newLatencyTelemetry(enabled bool) *latencyTelemetry {
@@ -66,11 +68,32 @@ func (t *latencyTelemetry) OnDebounceDropped(seq int) {
 	}
 }
 
+// OnFrame records the first screen of a query's output being shown, before
+// evaluation has finished.
+func (t *latencyTelemetry) OnFrame(seq int) {
+	if !t.enabled {
+		return
+	}
+	span, ok := t.pending[seq]
+	if !ok || span.framed {
+		return
+	}
+	span.framed = true
+	t.pending[seq] = span
+	if !span.queuedAt.IsZero() {
+		t.keyToFrame = append(t.keyToFrame, time.Since(span.queuedAt))
+	}
+}
+
 func (t *latencyTelemetry) OnResult(seq int, err error, accepted bool) {
 	if !t.enabled {
 		return
 	}
 
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.timedOut++
+	}
+
 	span, ok := t.pending[seq]
 	if !ok {
 		if errorsIsCanceled(err) {
@@ -84,8 +107,8 @@ func (t *latencyTelemetry) OnResult(seq int, err error, accepted bool) {
 	delete(t.pending, seq)
 
 	now := time.Now()
-	if accepted && !errorsIsCanceled(err) {
-		if !span.queuedAt.IsZero() {
+	if accepted && !errorsIsCanceled(err) && !errors.Is(err, context.DeadlineExceeded) {
+		if !span.queuedAt.IsZero() && !span.framed {
 			t.keyToFrame = append(t.keyToFrame, now.Sub(span.queuedAt))
 		}
 		if !span.dispatched.IsZero() {
@@ -105,6 +128,9 @@ func (t *latencyTelemetry) Summary() (string, bool) {
 		return "", false
 	}
 	if len(t.keyToFrame) == 0 {
+		if t.timedOut > 0 {
+			return fmt.Sprintf("telemetry: no completed samples yet | timeouts=%d", t.timedOut), true
+		}
 		return "telemetry: no completed samples yet", true
 	}
 
@@ -113,12 +139,12 @@ func (t *latencyTelemetry) Summary() (string, bool) {
 	runP50, runP95, runP99 := percentiles(t.runTime)
 
 	return fmt.Sprintf(
-		"telemetry keypress->frame samples=%d p50=%s p95=%s p99=%s | keypress->dispatch p50=%s p95=%s p99=%s | execute p50=%s p95=%s p99=%s | dropped(debounce)=%d stale=%d canceled=%d",
+		"telemetry keypress->frame samples=%d p50=%s p95=%s p99=%s | keypress->dispatch p50=%s p95=%s p99=%s | execute p50=%s p95=%s p99=%s | dropped(debounce)=%d stale=%d canceled=%d timeouts=%d",
 		len(t.keyToFrame),
 		keyP50, keyP95, keyP99,
 		startP50, startP95, startP99,
 		runP50, runP95, runP99,
-		t.droppedDebounce, t.staleResults, t.canceledResults,
+		t.droppedDebounce, t.staleResults, t.canceledResults, t.timedOut,
 	), true
 }
 
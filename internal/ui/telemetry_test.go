@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -44,3 +45,18 @@ func TestTelemetrySummaryNoSamples(t *testing.T) {
 		t.Fatalf("unexpected summary: %q", summary)
 	}
 }
+
+func TestTelemetryCountsTimeouts(t *testing.T) {
+	telemetry := newLatencyTelemetry(true)
+	telemetry.OnQueued(1)
+	telemetry.OnDispatch(1)
+	telemetry.OnResult(1, context.DeadlineExceeded, true)
+
+	if len(telemetry.runTime) != 0 {
+		t.Fatalf("timed out query recorded as a run of %v", telemetry.runTime)
+	}
+	summary, _ := telemetry.Summary()
+	if !strings.Contains(summary, "timeouts=1") {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+}
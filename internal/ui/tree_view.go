@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// Trees larger than this start with everything below the top level folded.
+const treeAutoFoldLines = 2000
+
+// treeLine is one row of the tree view.
+type treeLine struct {
+	text    string
+	result  int   // Index of the result the line belongs to
+	path    []any // jq path of the value, as string keys and int indices
+	fold    bool  // Opens or closes a non-empty container
+	closing bool  // The closing bracket of an expanded container
+}
+
+// treeState remembers which containers are folded, keyed by treeKey.
+type treeState struct {
+	folded map[string]bool
+}
+
+func newTreeState() *treeState {
+	return &treeState{folded: map[string]bool{}}
+}
+
+func treeKey(result int, path []any) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(result))
+	for _, p := range path {
+		b.WriteByte(0)
+		switch k := p.(type) {
+		case string:
+			b.WriteString("s" + k)
+		case int:
+			b.WriteString("i" + strconv.Itoa(k))
+		}
+	}
+	return b.String()
+}
+
+// autoFold folds every container below the top level of each result when
+// the fully expanded tree would be too long to scroll through.
+func (t *treeState) autoFold(values []any) {
+	count := 0
+	for _, v := range values {
+		count += countTreeLines(v, treeAutoFoldLines-count)
+		if count >= treeAutoFoldLines {
+			break
+		}
+	}
+	if count < treeAutoFoldLines {
+		return
+	}
+	for i, v := range values {
+		forEachChild(v, func(key any, child any) {
+			if containerLen(child) > 0 {
+				t.folded[treeKey(i, []any{key})] = true
+			}
+		})
+	}
+}
+
+// countTreeLines counts the lines v takes fully expanded, stopping once
+// limit is reached.
+func countTreeLines(v any, limit int) int {
+	n := containerLen(v)
+	if n == 0 {
+		return 1
+	}
+	count := 2
+	forEachChild(v, func(_ any, child any) {
+		if count < limit {
+			count += countTreeLines(child, limit-count)
+		}
+	})
+	return count
+}
+
+func containerLen(v any) int {
+	switch val := v.(type) {
+	case []any:
+		return len(val)
+	case map[string]any:
+		return len(val)
+	}
+	return 0
+}
+
+// forEachChild calls fn for each element of an array or object, with object
+// keys in sorted order to match the formatted output.
+func forEachChild(v any, fn func(key any, child any)) {
+	switch val := v.(type) {
+	case []any:
+		for i, item := range val {
+			fn(i, item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fn(k, val[k])
+		}
+	}
+}
+
+// lines lays out values as indented JSON, drawing folded containers on one
+// line with their child count.
+func (t *treeState) lines(values []any) []treeLine {
+	var out []treeLine
+	for i, v := range values {
+		out = t.appendLines(out, i, nil, "", v, 0, false)
+	}
+	return out
+}
+
+func (t *treeState) appendLines(out []treeLine, result int, path []any, prefix string, v any, depth int, comma bool) []treeLine {
+	indent := strings.Repeat("  ", depth)
+	suffix := ""
+	if comma {
+		suffix = ","
+	}
+
+	n := containerLen(v)
+	if n == 0 {
+		text := indent + prefix + jq.FormatValue(v, jq.OutputOptions{Compact: true}) + suffix
+		return append(out, treeLine{text: text, result: result, path: path})
+	}
+
+	open, close, unit := "{", "}", "keys"
+	if _, ok := v.([]any); ok {
+		open, close, unit = "[", "]", "items"
+	}
+	if n == 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	if t.folded[treeKey(result, path)] {
+		text := indent + prefix + open + "… " + strconv.Itoa(n) + " " + unit + close + suffix
+		return append(out, treeLine{text: text, result: result, path: path, fold: true})
+	}
+
+	out = append(out, treeLine{text: indent + prefix + open, result: result, path: path, fold: true})
+	i := 0
+	forEachChild(v, func(key any, child any) {
+		i++
+		childPath := append(append([]any{}, path...), key)
+		childPrefix := ""
+		if k, ok := key.(string); ok {
+			childPrefix = jq.FormatValue(k, jq.OutputOptions{}) + ": "
+		}
+		out = t.appendLines(out, result, childPath, childPrefix, child, depth+1, i < n)
+	})
+	return append(out, treeLine{text: indent + close + suffix, result: result, path: path, fold: true, closing: true})
+}
+
+// toggle folds or unfolds the container with the given path.
+func (t *treeState) toggle(result int, path []any) {
+	key := treeKey(result, path)
+	if t.folded[key] {
+		delete(t.folded, key)
+	} else {
+		t.folded[key] = true
+	}
+}
+
+// setFolded folds or unfolds every container in values.
+func (t *treeState) setFolded(values []any, folded bool) {
+	t.folded = map[string]bool{}
+	if !folded {
+		return
+	}
+	var walk func(result int, path []any, v any)
+	walk = func(result int, path []any, v any) {
+		if containerLen(v) == 0 {
+			return
+		}
+		t.folded[treeKey(result, path)] = true
+		forEachChild(v, func(key any, child any) {
+			walk(result, append(append([]any{}, path...), key), child)
+		})
+	}
+	for i, v := range values {
+		walk(i, nil, v)
+	}
+}
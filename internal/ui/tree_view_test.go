@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func treeTexts(lines []treeLine) []string {
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.text
+	}
+	return texts
+}
+
+func TestTreeLines(t *testing.T) {
+	values := []any{map[string]any{
+		"name":  "web",
+		"ports": []any{json.Number("80"), json.Number("443")},
+		"meta":  map[string]any{"team": "ops"},
+		"empty": []any{},
+	}}
+
+	tree := newTreeState()
+	want := []string{
+		"{",
+		`  "empty": [],`,
+		`  "meta": {`,
+		`    "team": "ops"`,
+		"  },",
+		`  "name": "web",`,
+		`  "ports": [`,
+		"    80,",
+		"    443",
+		"  ]",
+		"}",
+	}
+	if got := treeTexts(tree.lines(values)); !equalStringSlices(got, want) {
+		t.Fatalf("expanded =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// Fully expanded, the tree reads the same as the formatted output
+	if got := strings.Join(want, "\n"); got != jq.FormatValue(values[0], jq.OutputOptions{}) {
+		t.Fatalf("expanded tree differs from FormatValue:\n%s", got)
+	}
+
+	tree.toggle(0, []any{"ports"})
+	tree.toggle(0, []any{"meta"})
+	want = []string{
+		"{",
+		`  "empty": [],`,
+		`  "meta": {… 1 key},`,
+		`  "name": "web",`,
+		`  "ports": [… 2 items]`,
+		"}",
+	}
+	got := tree.lines(values)
+	if !equalStringSlices(treeTexts(got), want) {
+		t.Fatalf("folded =\n%s\nwant\n%s", strings.Join(treeTexts(got), "\n"), strings.Join(want, "\n"))
+	}
+	if !got[4].fold || got[4].path[0] != "ports" {
+		t.Fatalf("folded line = %+v, want foldable ports line", got[4])
+	}
+}
+
+func TestTreeAutoFold(t *testing.T) {
+	items := make([]any, treeAutoFoldLines)
+	for i := range items {
+		items[i] = map[string]any{"id": json.Number("1")}
+	}
+	values := []any{map[string]any{"items": items, "count": json.Number("1")}}
+
+	tree := newTreeState()
+	tree.autoFold(values)
+	want := []string{"{", `  "count": 1,`, `  "items": [… 2000 items]`, "}"}
+	if got := treeTexts(tree.lines(values)); !equalStringSlices(got, want) {
+		t.Fatalf("auto-folded = %q, want %q", got, want)
+	}
+}
+
+func TestBrowseFolding(t *testing.T) {
+	m := Model{
+		view:   viewTree,
+		mode:   ModeBrowse,
+		result: jq.Result{Values: []any{map[string]any{"a": []any{json.Number("1"), json.Number("2")}}}},
+	}
+	m.refreshLines()
+
+	press := func(key string) {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		switch key {
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "left":
+			msg = tea.KeyMsg{Type: tea.KeyLeft}
+		case "space":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		}
+		updated, _ := m.handleBrowseKey(msg)
+		m = updated.(Model)
+	}
+
+	press("down") // "a": [
+	press("space")
+	if want := []string{"{", `  "a": [… 2 items]`, "}"}; !equalStringSlices(m.lines, want) {
+		t.Fatalf("after fold = %q, want %q", m.lines, want)
+	}
+
+	press("space")
+	press("down") // 1,
+	press("left") // moves to the enclosing array
+	if m.cursor != 1 {
+		t.Fatalf("cursor = %d, want 1 after moving to parent", m.cursor)
+	}
+
+	press("C")
+	if want := []string{"{… 1 key}"}; !equalStringSlices(m.lines, want) {
+		t.Fatalf("after collapse all = %q, want %q", m.lines, want)
+	}
+	press("E")
+	if len(m.lines) != 6 {
+		t.Fatalf("after expand all got %d lines, want 6", len(m.lines))
+	}
+}
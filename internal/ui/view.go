@@ -1,12 +1,16 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/jq"
 )
 
 // Raw ANSI color codes for JSON syntax highlighting
@@ -47,6 +51,18 @@ func (m Model) renderView() string {
 	if m.mode == ModeHistory {
 		view = m.overlayHistory(view)
 	}
+	if m.mode == ModeSave {
+		view = m.overlaySave(view)
+	}
+	if m.mode == ModeExport {
+		view = m.overlayExport(view)
+	}
+	if m.mode == ModeFind {
+		view = m.overlayFind(view)
+	}
+	if m.mode == ModeViolations {
+		view = m.overlayViolations(view)
+	}
 
 	return view
 }
@@ -58,10 +74,16 @@ func (m Model) renderHeader() string {
 	var status string
 	if m.status != "" {
 		status = statusStyle.Render(m.status)
+	} else if m.loading {
+		status = helpStyle.Render("Loading input...")
 	} else if m.queryRunning {
 		status = helpStyle.Render("Running...")
+	} else if errors.As(m.result.Error, new(timeoutError)) {
+		status = errorStyle.Render(m.result.Error.Error())
 	} else if m.result.Error != nil {
 		status = errorStyle.Render("Error: " + m.result.Error.Error())
+	} else if m.buffer != nil && m.buffer.Truncated() {
+		status = statusStyle.Render(fmt.Sprintf("showing first %d results (truncated)", len(m.result.Values)))
 	}
 
 	return fmt.Sprintf("%s  %s\n%s\n", title, help, status)
@@ -70,33 +92,39 @@ func (m Model) renderHeader() string {
 func (m Model) renderContent() string {
 	outputWidth := m.outputContentWidth()
 
-	lines := m.lines
-	if len(lines) == 0 {
-		lines = []string{""}
-	}
-
-	startLine := m.output.YOffset
-	endLine := startLine + m.contentHeight()
-	if endLine > len(lines) {
-		endLine = len(lines)
-	}
-	if startLine > len(lines) {
-		startLine = len(lines)
+	visibleLines, bodyStart := m.visibleLines(m.contentHeight())
+	if m.loading {
+		visibleLines, bodyStart = m.loadingLines(outputWidth), 0
 	}
 
-	visibleLines := lines[startLine:endLine]
-
 	// Manually pad each line to width (preserves ANSI codes)
 	var paddedLines []string
-	for _, rawLine := range visibleLines {
+	for i, rawLine := range visibleLines {
 		clippedRaw, leftCut, rightCut := clipRawLine(rawLine, m.outputXOffset, outputWidth)
 		clippedRaw = withEllipsis(clippedRaw, outputWidth, leftCut, rightCut)
 
+		idx := bodyStart + i - m.pinnedLines
+		var highlights []highlightRange
+		if i >= m.pinnedLines && len(m.searchMatches) > 0 {
+			start := firstVisibleRune([]rune(rawLine), m.outputXOffset)
+			highlights = m.lineHighlights(idx, start, clippedRaw, leftCut, rightCut)
+		}
+
 		line := rawLine
-		if m.result.Error != nil {
+		switch {
+		case m.loading:
+			line = clippedRaw
+		case m.mode == ModeBrowse && i >= m.pinnedLines && idx == m.cursor:
+			line = cursorStyle.Render(clippedRaw)
+		case m.result.Error != nil:
 			line = errorStyle.Render(clippedRaw)
-		} else {
-			line = m.colorCache.Colorize(clippedRaw)
+		case i < m.pinnedLines:
+			line = labelStyle.Render(clippedRaw)
+		case m.pinnedLines > 0:
+			// Table cells are plain text, not JSON
+			line = highlightMatches(clippedRaw, highlights)
+		default:
+			line = highlightMatches(m.colorCache.Colorize(clippedRaw), highlights)
 		}
 
 		displayWidth := lipgloss.Width(line)
@@ -191,7 +219,14 @@ func colorizeJSON(s string) string {
 func (m Model) renderSuggestions() string {
 	if m.mode == ModeAutocomplete && len(m.suggestions) > 0 {
 		var lines []string
-		lines = append(lines, labelStyle.Render("Keys:"))
+		switch m.acContext.Kind {
+		case autocomplete.KindVariable:
+			lines = append(lines, labelStyle.Render("Variables:"))
+		case autocomplete.KindFunction:
+			lines = append(lines, labelStyle.Render("Functions:"))
+		default:
+			lines = append(lines, labelStyle.Render("Keys:"))
+		}
 		for i, s := range m.suggestions {
 			if i == m.selectedIdx {
 				lines = append(lines, selectedStyle.Render("→ "+s))
@@ -206,35 +241,145 @@ func (m Model) renderSuggestions() string {
 		return strings.Join(lines, "\n")
 	}
 
+	switch m.acContext.Kind {
+	case autocomplete.KindVariable:
+		names := filterKeysByPrefix(m.autocomplete.VariableNames(), m.acContext.Incomplete)
+		return m.renderNameList("Variables:", "No matching variables", names)
+	case autocomplete.KindFunction:
+		return m.renderNameList("Functions:", "No matching functions", m.functionNames)
+	}
+
+	// Reserve room at the bottom for any user-defined variables
+	varLines := m.variableLines(m.contentHeight() / 3)
+	keyHeight := m.contentHeight() - len(varLines)
+
 	// Show current path keys when not in autocomplete
+	var lines []string
 	allKeys := m.availableKeys
-	if m.keysInFlight == m.currentPath() && len(allKeys) == 0 {
-		return labelStyle.Render("Loading keys...")
-	}
 	keys := filterKeysByPrefix(allKeys, m.acContext.Incomplete)
-	if len(keys) == 0 {
+	switch {
+	case m.keysInFlight == m.currentPath() && len(allKeys) == 0:
+		lines = append(lines, labelStyle.Render("Loading keys..."))
+	case len(keys) == 0 && m.acContext.Incomplete != "":
+		lines = append(lines, labelStyle.Render("No matches"))
+	case len(keys) == 0:
+		lines = append(lines, labelStyle.Render("No keys"))
+	default:
 		if m.acContext.Incomplete != "" {
-			return labelStyle.Render("No matches")
+			lines = append(lines, labelStyle.Render("Matching keys:"))
+		} else {
+			lines = append(lines, labelStyle.Render("Available keys:"))
+		}
+		for i, k := range keys {
+			lines = append(lines, suggestionStyle.Render("  "+k))
+			if i >= keyHeight-2 {
+				lines = append(lines, helpStyle.Render(fmt.Sprintf("  ...+%d more", len(keys)-i-1)))
+				break
+			}
 		}
-		return labelStyle.Render("No keys")
 	}
+	return strings.Join(append(lines, varLines...), "\n")
+}
 
-	var lines []string
-	if m.acContext.Incomplete != "" {
-		lines = append(lines, labelStyle.Render("Matching keys:"))
-	} else {
-		lines = append(lines, labelStyle.Render("Available keys:"))
+// renderNameList lists completion candidates under label.
+func (m Model) renderNameList(label, empty string, names []string) string {
+	if len(names) == 0 {
+		return labelStyle.Render(empty)
 	}
-	for i, k := range keys {
-		lines = append(lines, suggestionStyle.Render("  "+k))
+	lines := []string{labelStyle.Render(label)}
+	for i, name := range names {
+		lines = append(lines, suggestionStyle.Render("  "+name))
 		if i >= m.contentHeight()-2 {
-			lines = append(lines, helpStyle.Render(fmt.Sprintf("  ...+%d more", len(keys)-i-1)))
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("  ...+%d more", len(names)-i-1)))
 			break
 		}
 	}
 	return strings.Join(lines, "\n")
 }
 
+// variablePreview is a user-defined variable as the side panel shows it.
+type variablePreview struct {
+	name    string
+	preview string
+}
+
+// variablePreviews previews the user-defined variables once, as values from
+// --slurpfile or --rawfile can be megabytes.
+func variablePreviews(vars []jq.Variable) []variablePreview {
+	var out []variablePreview
+	for _, v := range vars {
+		if v.Name != "$ARGS" {
+			out = append(out, variablePreview{name: v.Name, preview: previewValue(v.Value)})
+		}
+	}
+	return out
+}
+
+// previewRunes caps how much of a string a one-line preview shows.
+const previewRunes = 200
+
+// previewValue renders v on one line without formatting all of it:
+// containers show their size and long strings their start.
+func previewValue(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			return "{}"
+		}
+		return "{…} " + countNoun(len(v), "key")
+	case []any:
+		if len(v) == 0 {
+			return "[]"
+		}
+		return "[…] " + countNoun(len(v), "item")
+	case string:
+		n, cut := 0, len(v)
+		for i := range v {
+			if n == previewRunes {
+				cut = i
+				break
+			}
+			n++
+		}
+		if cut < len(v) {
+			return jq.FormatValue(v[:cut], jq.OutputOptions{Compact: true}) + "…"
+		}
+	}
+	return jq.FormatValue(v, jq.OutputOptions{Compact: true})
+}
+
+// countNoun renders n with noun, pluralised with an s.
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// variableLines renders the user-defined variables with a preview of their
+// values, using at most maxLines lines.
+func (m Model) variableLines(maxLines int) []string {
+	vars := m.variables
+	if len(vars) == 0 || maxLines < 3 {
+		return nil
+	}
+
+	lines := []string{"", labelStyle.Render("Variables:")}
+	width := m.suggestWidth() - 2
+	for i, v := range vars {
+		if len(lines) == maxLines-1 && i < len(vars)-1 {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("  ...+%d more", len(vars)-i)))
+			break
+		}
+		entry := []rune("  " + v.name + " = " + v.preview)
+		if trimmed := trimToDisplayWidth(entry, width); len(trimmed) < len(entry) {
+			entry = append(trimToDisplayWidth(trimmed, width-1), '…')
+		}
+		lines = append(lines, suggestionStyle.Render(string(entry)))
+	}
+	return lines
+}
+
 func filterKeysByPrefix(keys []string, incomplete string) []string {
 	if incomplete == "" || len(keys) == 0 {
 		return keys
@@ -261,16 +406,83 @@ func (m Model) currentPath() string {
 func (m Model) renderFooter() string {
 	filterLabel := labelStyle.Render("filter: ")
 	filter := m.filter.View()
+	if m.multiline {
+		// The editor sits on its own rows below the label
+		filterLabel = labelStyle.Render("filter (alt+enter: output, alt+m: single line):") + "\n"
+		filter = m.editor.View()
+	}
 	fileLabel := labelStyle.Render("file: ")
 	file := m.filename
+	if m.jq != nil && m.jq.NullInput() {
+		file += labelStyle.Render(" (null input)")
+	} else if m.jq != nil && m.jq.IsStream() {
+		file += labelStyle.Render(fmt.Sprintf(" (%d values)", len(m.jq.Inputs())))
+	}
+	if flags := m.outputFlags(); flags != "" {
+		file += labelStyle.Render(" out: " + flags)
+	}
+	file += m.schemaLabel()
+	if m.view != viewJSON {
+		view := " view: " + m.view.String()
+		if m.viewFallback {
+			view += " (n/a)"
+		}
+		if m.ungron {
+			view += " → json (ungron)"
+		}
+		file += labelStyle.Render(view)
+	}
 	scrollLabel := ""
 	if m.maxHorizontalOffset() > 0 {
 		scrollLabel = labelStyle.Render(fmt.Sprintf(" x:%d/%d", m.outputXOffset, m.maxHorizontalOffset()))
 	}
 
+	if label := m.searchLabel(); label != "" {
+		scrollLabel += labelStyle.Render(" " + label)
+	}
+
+	if m.mode == ModeSearch {
+		fileLabel = labelStyle.Render("search: ")
+		file = m.searchInput.View()
+		if label := m.searchLabel(); label != "" {
+			file += "  " + helpStyle.Render(label)
+		}
+		return fmt.Sprintf("\n%s%s\n%s%s", filterLabel, filter, fileLabel, file)
+	}
+
+	if m.mode == ModeBrowse {
+		// The filter is not taking keys; say what does
+		fileLabel = labelStyle.Render("path: ")
+		file = m.breadcrumb() + "  " + helpStyle.Render("y copy · p insert · / search · esc filter")
+		if m.view == viewTree && !m.viewFallback {
+			file += helpStyle.Render(" · space fold · E/C all")
+		}
+	}
+
 	return fmt.Sprintf("\n%s%s\n%s%s%s", filterLabel, filter, fileLabel, file, scrollLabel)
 }
 
+// outputFlags summarises the non-default output options for the footer.
+func (m Model) outputFlags() string {
+	if m.jq == nil {
+		return ""
+	}
+	opts := m.jq.OutputOptions()
+	var flags []string
+	if opts.YAML {
+		flags = append(flags, "yaml")
+	}
+	if opts.Raw {
+		flags = append(flags, "raw")
+	}
+	if opts.Compact {
+		flags = append(flags, "compact")
+	} else if opts.Tab {
+		flags = append(flags, "tab")
+	}
+	return strings.Join(flags, ",")
+}
+
 func (m Model) overlayHistory(base string) string {
 	if len(m.historyItems) == 0 {
 		content := "No history for this file"
@@ -301,6 +513,41 @@ func (m Model) overlayHistory(base string) string {
 	return placeOverlay(base, overlay, m.width, m.height)
 }
 
+func (m Model) overlayExport(base string) string {
+	lines := []string{
+		titleStyle.Render("Copy Output As"),
+		helpStyle.Render("↑/↓: navigate | enter: copy | esc: close"),
+		"",
+	}
+	for i, f := range jq.TableFormats {
+		item := fmt.Sprintf("%s  (%c)", f.Label(), f[0])
+		if i == m.exportIdx {
+			lines = append(lines, selectedStyle.Render("→ "+item))
+		} else {
+			lines = append(lines, suggestionStyle.Render("  "+item))
+		}
+	}
+
+	overlay := historyOverlayStyle.Render(strings.Join(lines, "\n"))
+	return placeOverlay(base, overlay, m.width, m.height)
+}
+
+func (m Model) overlaySave(base string) string {
+	lines := []string{
+		titleStyle.Render("Save Filter"),
+		helpStyle.Render("enter: save | esc: cancel"),
+		"",
+		m.saveInput.View(),
+	}
+	if m.saveConfirm {
+		path := filterFilePath(m.saveInput.Value())
+		lines = append(lines, "", statusStyle.Render(path+" exists. Overwrite? (y/n)"))
+	}
+
+	overlay := historyOverlayStyle.Render(strings.Join(lines, "\n"))
+	return placeOverlay(base, overlay, m.width, m.height)
+}
+
 func (m Model) compactHelpText() string {
 	items := []string{
 		"?: help",
@@ -310,6 +557,15 @@ func (m Model) compactHelpText() string {
 		"shift+left/right: h-scroll",
 		"ctrl+h: history",
 		"ctrl+y: copy out",
+		"ctrl+x: copy as table",
+		"alt+t: table view",
+		"alt+o: tree view",
+		"alt+g: gron",
+		"alt+k: schema",
+		"ctrl+s: save filter",
+		"alt+m: multi-line",
+		"alt+e: $EDITOR",
+		"alt+p: find value",
 	}
 
 	if m.width <= 0 {
@@ -369,13 +625,45 @@ func (m Model) renderHelpContent() string {
 		m.helpRow("Alt/Ctrl+Left", "Prev word"),
 		m.helpRow("Alt/Ctrl+Right", "Next word"),
 		m.helpRow("Alt/Ctrl+Backspace", "Delete prev word"),
+		m.helpRow("Alt+M", "Toggle multi-line editor"),
+		m.helpRow("Alt+Up/Down", "Resize multi-line editor"),
+		m.helpRow("Alt+Enter", "Output result (multi-line)"),
+		m.helpRow("Alt+E", "Edit filter in $EDITOR"),
 		"",
 		labelStyle.Render("Actions"),
 		m.helpRow("Enter", "Output result and quit"),
 		m.helpRow("Ctrl+Y", "Copy output"),
 		m.helpRow("Ctrl+F", "Copy filter"),
+		m.helpRow("Ctrl+X", "Copy as CSV/TSV/Markdown"),
 		m.helpRow("Ctrl+H", "Query history"),
+		m.helpRow("Alt+P", "Find paths to a key or value"),
+		m.helpRow("Alt+V", "Schema violations (with --schema)"),
+		m.helpRow("Ctrl+S", "Save filter to .jq file"),
 		m.helpRow("Esc/Ctrl+C", "Quit"),
+		"",
+		labelStyle.Render("Output"),
+		m.helpRow("Alt+R", "Toggle raw strings"),
+		m.helpRow("Alt+C", "Toggle compact output"),
+		m.helpRow("Alt+I", "Toggle tab indentation"),
+		m.helpRow("Alt+Y", "Toggle YAML output"),
+		m.helpRow("Alt+T", "Toggle table view"),
+		m.helpRow("Alt+O", "Toggle tree view"),
+		m.helpRow("Alt+G", "Toggle gron view"),
+		m.helpRow("Alt+K", "Toggle inferred schema (Ctrl+Y copies JSON Schema)"),
+		m.helpRow("Alt+U", "Ungron: gron lines matching the search back to JSON"),
+		m.helpRow("Alt+/", "Search output"),
+		"",
+		labelStyle.Render("Browse output"),
+		m.helpRow("Ctrl+O", "Focus output / filter"),
+		m.helpRow("Up/Down, j/k", "Move cursor"),
+		m.helpRow("y", "Copy path under cursor"),
+		m.helpRow("p / i", "Insert path into filter"),
+		m.helpRow("Space/Enter", "Fold or unfold (tree)"),
+		m.helpRow("Left/Right", "Fold, go to parent / unfold (tree)"),
+		m.helpRow("E / C", "Expand / collapse all (tree)"),
+		m.helpRow("/", "Search output (regex)"),
+		m.helpRow("n / N", "Next / previous match"),
+		m.helpRow("Esc", "Back to filter"),
 	}
 
 	panel := historyOverlayStyle.Width(maxWidth).Render(strings.Join(rows, "\n"))
@@ -452,20 +740,7 @@ func clipRawLine(line string, xOffset, maxWidth int) (string, bool, bool) {
 		xOffset = totalWidth
 	}
 
-	startIdx := len(runes)
-	widthSoFar := 0
-	for i, r := range runes {
-		w := runewidth.RuneWidth(r)
-		if w < 1 {
-			w = 1
-		}
-		if widthSoFar+w > xOffset {
-			startIdx = i
-			break
-		}
-		widthSoFar += w
-	}
-
+	startIdx := firstVisibleRune(runes, xOffset)
 	endIdx := startIdx
 	visibleWidth := 0
 	for i := startIdx; i < len(runes); i++ {
@@ -485,6 +760,23 @@ func clipRawLine(line string, xOffset, maxWidth int) (string, bool, bool) {
 	return string(runes[startIdx:endIdx]), leftCut, rightCut
 }
 
+// firstVisibleRune returns the index of the first rune not scrolled past
+// xOffset columns.
+func firstVisibleRune(runes []rune, xOffset int) int {
+	widthSoFar := 0
+	for i, r := range runes {
+		w := runewidth.RuneWidth(r)
+		if w < 1 {
+			w = 1
+		}
+		if widthSoFar+w > xOffset {
+			return i
+		}
+		widthSoFar += w
+	}
+	return len(runes)
+}
+
 func withEllipsis(line string, maxWidth int, leftCut, rightCut bool) string {
 	if maxWidth <= 0 {
 		return ""
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+// inputViolationsMsg carries the result of validating the input against
+// the --schema file.
+type inputViolationsMsg struct {
+	violations []jq.Violation
+}
+
+// validateInput checks every input value against the schema in the
+// background; large documents can take a moment.
+func (m Model) validateInput() tea.Cmd {
+	validator, inputs := m.validator, m.jq.Inputs()
+	return func() tea.Msg {
+		return inputViolationsMsg{violations: validator.ValidateAll(inputs)}
+	}
+}
+
+// resultViolationsMsg carries the result of validating the outputs of query
+// seq against the schema.
+type resultViolationsMsg struct {
+	seq        int
+	violations []jq.Violation
+}
+
+// validateResultValues checks the results of query seq in the background,
+// like validateInput, so large results do not stall typing.
+func (m Model) validateResultValues(seq int) tea.Cmd {
+	validator, values := m.validator, m.result.Values
+	return func() tea.Msg {
+		return resultViolationsMsg{seq: seq, violations: validator.ValidateAll(values)}
+	}
+}
+
+// applyResultViolations records the violations of the shown results,
+// dropping replies for queries since replaced.
+func (m Model) applyResultViolations(msg resultViolationsMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.resultCheckSeq {
+		return m, nil
+	}
+	m.resultCheckSeq = 0
+	m.resultViolations = msg.violations
+	return m, nil
+}
+
+// violationEntry is a row of the violations pane.
+type violationEntry struct {
+	source    string // "input" or "result"
+	multiple  bool   // Several values were validated, so Index matters
+	violation jq.Violation
+}
+
+// violationEntries lists input violations, then result violations.
+func (m Model) violationEntries() []violationEntry {
+	var entries []violationEntry
+	for _, v := range m.inputViolations {
+		entries = append(entries, violationEntry{source: "input", multiple: m.jq.IsStream(), violation: v})
+	}
+	for _, v := range m.resultViolations {
+		entries = append(entries, violationEntry{source: "result", multiple: len(m.result.Values) > 1, violation: v})
+	}
+	return entries
+}
+
+func (m Model) openViolations() (tea.Model, tea.Cmd) {
+	switch {
+	case m.validator == nil:
+		m.status = "No schema; start gijq with --schema file.json"
+		return m, clearStatusAfter(3 * time.Second)
+	case !m.inputValidated:
+		m.status = "Validating input..."
+		return m, clearStatusAfter(3 * time.Second)
+	}
+	m.mode = ModeViolations
+	m.violationIdx = 0
+	m.suggestions = nil
+	return m, nil
+}
+
+func (m Model) handleViolationsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	entries := m.violationEntries()
+	switch msg.String() {
+	case "up", "k":
+		if m.violationIdx > 0 {
+			m.violationIdx--
+		}
+	case "down", "j":
+		if m.violationIdx < len(entries)-1 {
+			m.violationIdx++
+		}
+	case "enter":
+		if m.violationIdx >= len(entries) {
+			return m, nil
+		}
+		e := entries[m.violationIdx]
+		filter := jq.FormatPath(e.violation.Path)
+		// Result paths are relative to the result, so they extend the filter
+		if e.source == "result" {
+			filter = pathFilter(m.filterValue(), filter)
+		}
+		m.setFilter(filter, len([]rune(filter)))
+		m.mode = ModeNormal
+		m.refreshContext()
+		return m, tea.Batch(m.executeNow(), m.maybeFetchKeys())
+	}
+	return m, nil
+}
+
+// schemaLabel summarises validation for the footer.
+func (m Model) schemaLabel() string {
+	if m.validator == nil {
+		return ""
+	}
+	if !m.inputValidated || m.resultCheckSeq != 0 {
+		return labelStyle.Render(" schema: checking")
+	}
+	n := len(m.inputViolations) + len(m.resultViolations)
+	if n == 0 {
+		return labelStyle.Render(" schema: ok")
+	}
+	return errorStyle.Render(fmt.Sprintf(" schema: %d violations (alt+v)", n))
+}
+
+// violationLabel describes an entry as where it is, then what is wrong.
+func violationLabel(e violationEntry, width int) string {
+	where := e.source
+	if e.multiple {
+		where = fmt.Sprintf("%s %d", e.source, e.violation.Index+1)
+	}
+	entry := []rune(fmt.Sprintf("%-9s %s  %s", where, jq.FormatPath(e.violation.Path), e.violation.Message))
+	if trimmed := trimToDisplayWidth(entry, width); len(trimmed) < len(entry) {
+		entry = append(trimToDisplayWidth(trimmed, width-1), '…')
+	}
+	return string(entry)
+}
+
+func (m Model) overlayViolations(base string) string {
+	lines := []string{
+		titleStyle.Render("Schema Violations"),
+		helpStyle.Render("schema: " + m.schemaPath),
+		helpStyle.Render("↑/↓: navigate | enter: jump filter to path | esc: close"),
+		"",
+	}
+
+	entries := m.violationEntries()
+	if len(entries) == 0 {
+		lines = append(lines, selectedStyle.Render("No violations"))
+	}
+
+	width := m.width - 12
+	if width < 20 {
+		width = 20
+	}
+	const maxShow = 12
+	first := 0
+	if m.violationIdx >= maxShow {
+		first = m.violationIdx - maxShow + 1
+	}
+	for i := first; i < len(entries) && i < first+maxShow; i++ {
+		item := violationLabel(entries[i], width)
+		if i == m.violationIdx {
+			lines = append(lines, selectedStyle.Render("→ "+item))
+		} else {
+			lines = append(lines, suggestionStyle.Render("  "+item))
+		}
+	}
+	if len(entries) > first+maxShow {
+		lines = append(lines, helpStyle.Render(fmt.Sprintf("...+%d more", len(entries)-first-maxShow)))
+	}
+
+	overlay := historyOverlayStyle.Render(strings.Join(lines, "\n"))
+	return placeOverlay(base, overlay, m.width, m.height)
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dayangraham/gijq/internal/autocomplete"
+	"github.com/dayangraham/gijq/internal/history"
+	"github.com/dayangraham/gijq/internal/jq"
+)
+
+func TestViolationsJumpToPath(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.json")
+	schema := `{"properties": {"users": {"items": {"properties": {"age": {"type": "integer"}}}}}}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := jq.LoadSchema(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := jq.NewService([]byte(`{"users":[{"age":30},{"age":"old"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist, err := history.NewStore(filepath.Join(dir, "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{Schema: validator, SchemaPath: schemaPath})
+
+	// The pane waits for validation to finish
+	updated, _ := m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v"), Alt: true})
+	m = updated.(Model)
+	if m.mode == ModeViolations {
+		t.Fatal("violations opened before validation finished")
+	}
+
+	updated, _ = m.Update(m.validateInput()())
+	m = updated.(Model)
+	if !strings.Contains(m.schemaLabel(), "1 violations") {
+		t.Fatalf("schema label = %q", m.schemaLabel())
+	}
+
+	updated, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v"), Alt: true})
+	m = updated.(Model)
+	if m.mode != ModeViolations {
+		t.Fatalf("mode = %v, want violations", m.mode)
+	}
+	updated, _ = m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(Model)
+	if got := m.filterValue(); got != ".users[1].age" {
+		t.Fatalf("filter = %q, want .users[1].age", got)
+	}
+	if m.mode != ModeNormal {
+		t.Fatalf("mode = %v, want normal", m.mode)
+	}
+}
+
+func TestResultViolationLabel(t *testing.T) {
+	m := Model{
+		resultViolations: []jq.Violation{{Index: 1, Path: []any{"id"}, Message: "got string, want integer"}},
+		result:           jq.Result{Values: []any{1, 2}},
+	}
+	entries := m.violationEntries()
+	if len(entries) != 1 || !entries[0].multiple {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if got := violationLabel(entries[0], 80); got != "result 2  .id  got string, want integer" {
+		t.Errorf("label = %q", got)
+	}
+}
+
+func TestResultValidationRunsInBackground(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(`{"type": "integer"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := jq.LoadSchema(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := jq.NewService([]byte(`{"a":1,"b":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist, err := history.NewStore(filepath.Join(dir, "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(svc, autocomplete.NewService(svc), hist, nil, Config{Filter: ".b", Schema: validator, ValidateResults: true})
+	updated, _ := m.Update(inputViolationsMsg{})
+	m = updated.(Model)
+
+	m, check := finishQuery(m, m.executeNow())
+	if check == nil || m.resultViolations != nil || !strings.Contains(m.schemaLabel(), "checking") {
+		t.Fatalf("results should be validated by a command; label = %q", m.schemaLabel())
+	}
+	stale := check()
+
+	// A newer query's results replace the pending check
+	m.setFilter(".a", 2)
+	m, check = finishQuery(m, m.executeNow())
+	updated, _ = m.Update(stale)
+	m = updated.(Model)
+	if m.resultViolations != nil {
+		t.Fatalf("stale violations applied: %+v", m.resultViolations)
+	}
+	updated, _ = m.Update(check())
+	m = updated.(Model)
+	if len(m.resultViolations) != 0 || !strings.Contains(m.schemaLabel(), "schema: ok") {
+		t.Fatalf("violations = %+v, label = %q", m.resultViolations, m.schemaLabel())
+	}
+}
+
+// finishQuery feeds a query's results to m until it finishes, returning the
+// command left once they are all in.
+func finishQuery(m Model, run tea.Cmd) (Model, tea.Cmd) {
+	cmd := run
+	for {
+		msg := cmd()
+		if _, ok := msg.(resultMsg); !ok {
+			return m, func() tea.Msg { return msg }
+		}
+		var updated tea.Model
+		updated, cmd = m.Update(msg)
+		m = updated.(Model)
+		if cmd == nil {
+			return m, nil
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -30,16 +31,96 @@ func run() error {
 		return nil
 	}
 
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, usageText())
+	}
+
+	if opts.fromFile != "" {
+		program, err := os.ReadFile(opts.fromFile)
+		if err != nil {
+			return fmt.Errorf("failed to read filter: %w", err)
+		}
+		opts.filter = string(program)
+	}
+
+	var validator *jq.Validator
+	if opts.schema != "" {
+		validator, err = jq.LoadSchema(opts.schema)
+		if err != nil {
+			return fmt.Errorf("failed to load schema: %w", err)
+		}
+	}
+
+	timeout, err := opts.queryTimeout(strings.TrimSpace(os.Getenv("GIJQ_TIMEOUT")))
+	if err != nil {
+		return err
+	}
+
+	vars, err := resolveVariables(opts.vars)
+	if err != nil {
+		return err
+	}
+
 	// Determine input source
-	jsonData, filename, filepath, err := loadInput()
+	input, size, filename, filepath, err := openInput(opts.file, opts.nullInput)
 	if err != nil {
 		return err
 	}
+	if input != nil {
+		defer input.Close()
+	}
+
+	format := opts.inputFormat
+	if format == "" {
+		format = jq.FormatForPath(opts.file)
+	}
 
-	// Create services
-	jqSvc, err := jq.NewService(jsonData)
+	// Create services. The TUI starts while the input is read, so a large
+	// file shows its progress and the filter can be typed meanwhile.
+	cfg := jq.Config{
+		InputFormat: format,
+		Stream:      opts.stream,
+		Slurp:       opts.slurp,
+		NullInput:   opts.nullInput,
+		Output: jq.OutputOptions{
+			Raw:     opts.raw,
+			Compact: opts.compact,
+			Tab:     opts.tab,
+			YAML:    opts.yaml,
+			Gron:    opts.gron,
+			Schema:  opts.inferSchema,
+			Table:   opts.table,
+		},
+		Variables:   vars,
+		ModulePaths: opts.libPaths,
+		Limits:      opts.limits(),
+	}
+	var jqSvc *jq.Service
+	switch {
+	case input == nil:
+		jqSvc, err = jq.NewServiceWithConfig(nil, cfg)
+	case opts.batch:
+		jqSvc, err = jq.NewServiceFromReader(input, cfg)
+	default:
+		jqSvc = jq.NewLoadingService(cfg)
+		go func() { _ = jqSvc.Load(input, size) }() // Reported by WaitLoaded
+	}
+	parseErr := func(err error) error {
+		return fmt.Errorf("failed to parse %s: %w", strings.ToUpper(string(format)), err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
+		return parseErr(err)
+	}
+
+	if opts.batch {
+		if err := runBatch(jqSvc, opts.filter, os.Stdout); err != nil {
+			return err
+		}
+		if validator != nil {
+			return validateBatch(jqSvc, opts.filter, validator, opts.validateOut, os.Stderr)
+		}
+		return nil
 	}
 
 	acSvc := autocomplete.NewService(jqSvc)
@@ -55,48 +136,146 @@ func run() error {
 
 	// Create and run TUI
 	model := ui.NewModel(jqSvc, acSvc, hist, clip, ui.Config{
-		Filename:  filename,
-		Filepath:  filepath,
-		Telemetry: telemetryEnabled,
+		Filename:   filename,
+		Filepath:   filepath,
+		Filter:     opts.filter,
+		FilterFile: opts.fromFile,
+		Telemetry:  telemetryEnabled,
+		Timeout:    timeout,
+
+		Schema:          validator,
+		SchemaPath:      opts.schema,
+		ValidateResults: opts.validateOut,
 	})
 
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	programOpts := []tea.ProgramOption{tea.WithAltScreen()}
+	if tty := terminalOutput(); tty != nil {
+		defer tty.Close()
+		programOpts = append(programOpts, tea.WithOutput(tty))
+	}
+
+	p := tea.NewProgram(model, programOpts...)
 	finalModel, err := p.Run()
 	if telemetryEnabled {
 		printTelemetrySummary(finalModel, os.Stderr)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	// The TUI quits by itself when the input fails to load
+	if jqSvc.Loaded() {
+		if err := jqSvc.WaitLoaded(); err != nil {
+			return parseErr(err)
+		}
+	}
+	return printSelectedOutput(finalModel, os.Stdout)
+}
+
+// terminalOutput opens the controlling terminal for the TUI when stdout is
+// redirected, so the selected result can be piped while the UI stays visible.
+func terminalOutput() *os.File {
+	stat, err := os.Stdout.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice != 0 {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil
+	}
+	return tty
 }
 
-func loadInput() ([]byte, string, string, error) {
-	// Check for piped input
+// runBatch executes filter once and writes the result to w as it is
+// produced, for scripts and CI that reuse queries built interactively.
+func runBatch(jqSvc *jq.Service, filter string, w io.Writer) error {
+	if filter == "" {
+		filter = "."
+	}
+	return jqSvc.WriteResults(context.Background(), filter, w)
+}
+
+// validateBatch reports schema violations in the input, and with results
+// set in the filter's results, one per line on w. Any violation is an error
+// so scripts can gate on the exit status.
+func validateBatch(jqSvc *jq.Service, filter string, validator *jq.Validator, results bool, w io.Writer) error {
+	report := func(source string, multiple bool, violations []jq.Violation) error {
+		for _, v := range violations {
+			where := source
+			if multiple {
+				where = fmt.Sprintf("%s %d", source, v.Index+1)
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s: %s\n", where, jq.FormatPath(v.Path), v.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	inputs := jqSvc.Inputs()
+	violations := validator.ValidateAll(inputs)
+	if err := report("input", len(inputs) > 1, violations); err != nil {
+		return err
+	}
+	n := len(violations)
+	if results {
+		if filter == "" {
+			filter = "."
+		}
+		values := jqSvc.Execute(filter).Values
+		violations = validator.ValidateAll(values)
+		if err := report("result", len(values) > 1, violations); err != nil {
+			return err
+		}
+		n += len(violations)
+	}
+	if n > 0 {
+		return fmt.Errorf("%d schema violations", n)
+	}
+	return nil
+}
+
+// openInput opens the input document and returns its size when known. With
+// allowEmpty set (null-input mode) a missing input is not an error and the
+// reader is nil.
+func openInput(path string, allowEmpty bool) (io.ReadCloser, int64, string, string, error) {
+	// Check for piped input when no file is named
 	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		// Reading from pipe
-		data, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			return nil, "", "", fmt.Errorf("failed to read stdin: %w", err)
+	if (path == "" || path == "-") && (stat.Mode()&os.ModeCharDevice) == 0 {
+		var size int64
+		if stat.Mode().IsRegular() {
+			size = stat.Size() // Redirected from a file
 		}
-		return data, "<stdin>", "<stdin>", nil
+		return os.Stdin, size, "<stdin>", "<stdin>", nil
 	}
 
 	// Read from file argument
-	if len(os.Args) < 2 {
-		return nil, "", "", errors.New(usageText())
+	if path == "" || path == "-" {
+		if allowEmpty {
+			return nil, 0, "<null>", "<null>", nil
+		}
+		return nil, 0, "", "", errors.New(usageText())
 	}
 
-	path := os.Args[1]
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		absPath = path
 	}
 
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
+	if err == nil {
+		stat, err = f.Stat()
+		if err == nil && stat.IsDir() {
+			err = errors.New("is a directory")
+		}
+		if err != nil {
+			f.Close()
+		}
+	}
 	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to read %s: %w", path, err)
+		return nil, 0, "", "", fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	return data, filepath.Base(path), absPath, nil
+	return f, stat.Size(), filepath.Base(path), absPath, nil
 }
 
 func wantsHelp(args []string) bool {
@@ -116,9 +295,46 @@ func usageText() string {
 	lines := []string{
 		"usage: gijq <file.json>",
 		"       cat file.json | gijq",
+		"       gijq -f '.items[]' --batch file.json",
 		"",
 		"options:",
-		"  -h, --help   show help",
+		"  -f, --filter text  start with this filter instead of .",
+		"  --from-file file   start with the jq program in file (saved back with ctrl+s)",
+		"  --batch, --print   print the filter result to stdout without the TUI",
+		"  --schema file      validate the input against a JSON Schema (alt+v lists",
+		"                     violations; with --batch they go to stderr)",
+		"  --validate-results also validate each result against --schema",
+		"  --max-results n    show at most n results in the TUI (default 1000000, 0: no limit)",
+		"  --max-output-bytes size",
+		"                     show at most size bytes of output in the TUI, such as 64M",
+		"                     (default 256M, 0: no limit); enter and --batch print everything",
+		"  --timeout duration stop TUI queries running longer than this, such as 10s or",
+		"                     500ms (default 30s, also $GIJQ_TIMEOUT; 0: no limit)",
+		"  --input-format fmt json, yaml, toml, csv, tsv, xml or gron",
+		"                     (default: from the file extension, else json)",
+		"  --ndjson           treat input as JSON Lines (one value per line)",
+		"  --ungron           read gron assignments back into JSON (--input-format gron)",
+		"  -s, --slurp        read all inputs into one array",
+		"  -n, --null-input   use null as input; read inputs with input/inputs",
+		"  -r, --raw-output   write strings without quotes",
+		"  -c, --compact-output",
+		"                     write each result on a single line",
+		"  --tab              indent with tabs",
+		"  -y, --yaml-output  write results as YAML",
+		"  --gron             write results as gron assignments (json.a[0] = 1;)",
+		"  --infer-schema     write a JSON Schema inferred from the results",
+		"  --csv, --tsv, --markdown",
+		"                     write results as a table, one row per object",
+		"  -S, --sort-keys    sort object keys (always on; accepted for jq compatibility)",
+		"  --arg name value   bind $name to the string value",
+		"  --argjson name text",
+		"                     bind $name to the JSON text",
+		"  --slurpfile name file",
+		"                     bind $name to an array of the JSON values in file",
+		"  --rawfile name file",
+		"                     bind $name to the contents of file as a string",
+		"  -L directory       search directory for modules (~/.jq is always searched)",
+		"  -h, --help         show help",
 	}
 	return strings.Join(lines, "\n")
 }
@@ -136,6 +352,17 @@ func envEnabled(name string) bool {
 	return v == "1" || v == "true" || v == "yes" || v == "on"
 }
 
+func printSelectedOutput(model tea.Model, w io.Writer) error {
+	var err error
+	switch m := model.(type) {
+	case ui.Model:
+		_, err = m.WriteOutput(w)
+	case *ui.Model:
+		_, err = m.WriteOutput(w)
+	}
+	return err
+}
+
 func printTelemetrySummary(model tea.Model, w io.Writer) {
 	switch m := model.(type) {
 	case ui.Model:
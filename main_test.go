@@ -2,8 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/dayangraham/gijq/internal/jq"
 )
 
 func TestWantsHelp(t *testing.T) {
@@ -66,3 +73,242 @@ func TestEnvEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr bool
+	}{
+		{name: "no args", args: nil, want: options{}},
+		{name: "file", args: []string{"data.json"}, want: options{file: "data.json"}},
+		{name: "ndjson", args: []string{"--ndjson", "logs.ndjson"}, want: options{file: "logs.ndjson", stream: true}},
+		{name: "jsonl alias", args: []string{"logs.jsonl", "--jsonl"}, want: options{file: "logs.jsonl", stream: true}},
+		{name: "double dash", args: []string{"--", "-odd.json"}, want: options{file: "-odd.json"}},
+		{name: "slurp", args: []string{"--slurp", "a.json"}, want: options{file: "a.json", slurp: true}},
+		{name: "null input", args: []string{"-n"}, want: options{nullInput: true}},
+		{name: "grouped short flags", args: []string{"-sn", "a.json"}, want: options{file: "a.json", slurp: true, nullInput: true}},
+		{name: "grouped flags as values", args: []string{"--arg", "x", "-rc", "-f", "-sn", "-c"}, want: options{filter: "-sn", compact: true, vars: []namedArg{{flag: "--arg", name: "x", value: "-rc"}}}},
+		{name: "grouped flags after double dash", args: []string{"-n", "--", "-sn"}, want: options{file: "-sn", nullInput: true}},
+		{name: "output flags", args: []string{"-rc", "--tab", "-S", "a.json"}, want: options{file: "a.json", raw: true, compact: true, tab: true}},
+		{name: "yaml", args: []string{"--input-format", "YAML", "-cy", "cfg"}, want: options{file: "cfg", inputFormat: jq.FormatYAML, compact: true, yaml: true}},
+		{name: "table output", args: []string{"--md", "rows.json"}, want: options{file: "rows.json", table: jq.TableMarkdown}},
+		{name: "yaml and table", args: []string{"-y", "--csv"}, wantErr: true},
+		{name: "gron", args: []string{"--gron", "--ungron", "flat.txt"}, want: options{file: "flat.txt", inputFormat: jq.FormatGron, gron: true}},
+		{name: "gron and table", args: []string{"--gron", "--tsv"}, wantErr: true},
+		{name: "infer schema", args: []string{"--infer-schema", "-c"}, want: options{inferSchema: true, compact: true}},
+		{name: "infer schema and gron", args: []string{"--infer-schema", "--gron"}, wantErr: true},
+		{name: "unknown input format", args: []string{"--input-format", "ini"}, wantErr: true},
+		{name: "long output flags", args: []string{"--raw-output", "--compact-output"}, want: options{raw: true, compact: true}},
+		{
+			name: "named args",
+			args: []string{"--arg", "user", "alice", "a.json", "--argjson", "limit", "10"},
+			want: options{file: "a.json", vars: []namedArg{
+				{flag: "--arg", name: "user", value: "alice"},
+				{flag: "--argjson", name: "limit", value: "10"},
+			}},
+		},
+		{name: "library paths", args: []string{"-L", "lib", "-L./vendor/jq", "--library-path", "x"}, want: options{libPaths: []string{"lib", "./vendor/jq", "x"}}},
+		{name: "initial filter", args: []string{"-f", ".items[]", "data.json"}, want: options{file: "data.json", filter: ".items[]"}},
+		{name: "batch", args: []string{"--filter", ".a", "--batch"}, want: options{filter: ".a", batch: true}},
+		{name: "print alias", args: []string{"--print", "data.json"}, want: options{file: "data.json", batch: true}},
+		{name: "from file", args: []string{"--from-file", "q.jq", "d.json"}, want: options{file: "d.json", fromFile: "q.jq"}},
+		{name: "schema", args: []string{"--schema", "s.json", "--validate-results", "d.json"}, want: options{file: "d.json", schema: "s.json", validateOut: true}},
+		{name: "validate results without schema", args: []string{"--validate-results"}, wantErr: true},
+		{name: "schema missing file", args: []string{"--schema"}, wantErr: true},
+		{name: "limits", args: []string{"--max-results", "10", "--max-output-bytes", "0"}, want: options{maxResults: intPtr(10), maxBytes: intPtr(0)}},
+		{name: "negative limit", args: []string{"--max-results", "-1"}, wantErr: true},
+		{name: "timeout", args: []string{"--timeout", "1.5s"}, want: options{timeout: durationPtr(1500 * time.Millisecond)}},
+		{name: "timeout seconds", args: []string{"--timeout", "0"}, want: options{timeout: durationPtr(0)}},
+		{name: "bad timeout", args: []string{"--timeout", "soon"}, wantErr: true},
+		{name: "missing timeout", args: []string{"--timeout"}, wantErr: true},
+		{name: "filter and from file", args: []string{"-f", ".", "--from-file", "q.jq"}, wantErr: true},
+		{name: "filter missing value", args: []string{"-f"}, wantErr: true},
+		{name: "arg missing value", args: []string{"--arg", "user"}, wantErr: true},
+		{name: "unknown flag", args: []string{"--nope"}, wantErr: true},
+		{name: "two files", args: []string{"a.json", "b.json"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseArgs(%v) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
+func TestQueryTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", want: defaultTimeout},
+		{name: "env", env: "2m", want: 2 * time.Minute},
+		{name: "env seconds", env: "5", want: 5 * time.Second},
+		{name: "flag beats env", args: []string{"--timeout", "0"}, env: "5", want: 0},
+		{name: "bad env", env: "-1s", wantErr: true},
+		{name: "infinite env", env: "inf", wantErr: true},
+		{name: "env out of range", env: "1e300", wantErr: true},
+		{name: "not a number env", env: "NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := opts.queryTimeout(tt.env)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("queryTimeout(%q) = %v, %v; want %v (error %v)", tt.env, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		text    string
+		bytes   bool
+		want    int
+		wantErr bool
+	}{
+		{text: "42", want: 42},
+		{text: "0", want: 0},
+		{text: "64M", bytes: true, want: 64 << 20},
+		{text: "2kb", bytes: true, want: 2048},
+		{text: "1G", bytes: true, want: 1 << 30},
+		{text: "64M", wantErr: true},
+		{text: "-5", wantErr: true},
+		{text: "lots", bytes: true, wantErr: true},
+		{text: "99999999999G", bytes: true, wantErr: true},
+		{text: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.text, tt.bytes)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSize(%q, %v) = %d, %v; want %d (error %v)", tt.text, tt.bytes, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	opts, err := parseArgs([]string{"--max-output-bytes", "1K"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := opts.limits(); got != (jq.Limits{Results: jq.DefaultLimits.Results, Bytes: 1024}) {
+		t.Errorf("limits = %+v", got)
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "ids.json")
+	rawPath := filepath.Join(dir, "note.txt")
+	if err := os.WriteFile(jsonPath, []byte("1 2\n3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rawPath, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := resolveVariables([]namedArg{
+		{flag: "--arg", name: "s", value: "42"},
+		{flag: "--argjson", name: "j", value: `{"a":[1]}`},
+		{flag: "--slurpfile", name: "ids", value: jsonPath},
+		{flag: "--rawfile", name: "note", value: rawPath},
+	})
+	if err != nil {
+		t.Fatalf("resolveVariables failed: %v", err)
+	}
+
+	want := map[string]any{
+		"s":    "42",
+		"j":    map[string]any{"a": []any{json.Number("1")}},
+		"ids":  []any{json.Number("1"), json.Number("2"), json.Number("3")},
+		"note": "hello\n",
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Fatalf("resolveVariables = %#v, want %#v", vars, want)
+	}
+
+	if _, err := resolveVariables([]namedArg{{flag: "--argjson", name: "bad", value: "{"}}); err == nil {
+		t.Fatal("expected error for invalid --argjson")
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	jqSvc, err := jq.NewService([]byte(`{"items":[{"id":1},{"id":2}]}`))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runBatch(jqSvc, ".items[].id", &buf); err != nil {
+		t.Fatalf("runBatch failed: %v", err)
+	}
+	if buf.String() != "1\n2\n" {
+		t.Fatalf("runBatch output = %q, want %q", buf.String(), "1\n2\n")
+	}
+
+	buf.Reset()
+	if err := runBatch(jqSvc, "empty", &buf); err != nil || buf.Len() != 0 {
+		t.Fatalf("runBatch(empty) = (%q, %v), want no output", buf.String(), err)
+	}
+
+	if err := runBatch(jqSvc, ".[", &buf); err == nil {
+		t.Fatal("runBatch should fail on a parse error")
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(`{"properties": {"id": {"type": "integer"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := jq.LoadSchema(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jqSvc, err := jq.NewService([]byte(`{"id":"a","items":[{"id":1},{"id":"b"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = validateBatch(jqSvc, ".items[]", validator, false, &buf)
+	if err == nil || err.Error() != "1 schema violations" {
+		t.Fatalf("validateBatch error = %v", err)
+	}
+	if got := buf.String(); got != "input: .id: got string, want integer\n" {
+		t.Fatalf("input report = %q", got)
+	}
+
+	buf.Reset()
+	err = validateBatch(jqSvc, ".items[]", validator, true, &buf)
+	if err == nil || err.Error() != "2 schema violations" {
+		t.Fatalf("validateBatch error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "result 2: .id: got string, want integer\n") {
+		t.Fatalf("result report = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := validateBatch(jqSvc, ".items[0]", validator, true, &buf); err == nil {
+		t.Fatal("input violations should still fail")
+	}
+}